@@ -0,0 +1,333 @@
+package mongoutils
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type actorKey struct{}
+
+// Auditable model with audit trail
+// model inserts, updates, patches and deletes recorded to audit collection
+type Auditable interface {
+	// AuditCollection get audit records collection
+	AuditCollection(db *mongo.Database) *mongo.Collection
+}
+
+// AuditChange changed path of audited document
+type AuditChange struct {
+	Path   string `bson:"path" json:"path"`
+	Before any    `bson:"before" json:"before"`
+	After  any    `bson:"after" json:"after"`
+}
+
+// AuditRecord audit trail record
+type AuditRecord struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Action     string             `bson:"action" json:"action"`
+	TypeName   string             `bson:"type" json:"type"`
+	Collection string             `bson:"collection" json:"collection"`
+	DocumentID primitive.ObjectID `bson:"document_id" json:"document_id"`
+	Actor      any                `bson:"actor" json:"actor"`
+	Tenant     string             `bson:"tenant,omitempty" json:"tenant,omitempty"`
+	Changes    []AuditChange      `bson:"changes" json:"changes"`
+	Filter     any                `bson:"filter,omitempty" json:"filter,omitempty"`
+	Update     any                `bson:"update,omitempty" json:"update,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// auditBatchLimit max records audited per document on batch operations
+var auditBatchLimit int64 = 1000
+
+// SetAuditBatchLimit set max records audited per document on batch operations
+// batch operations matched more records recorded as single filter level audit record
+// pass 0 to always record filter level audit record
+func SetAuditBatchLimit(limit int64) {
+	if limit >= 0 {
+		auditBatchLimit = limit
+	}
+}
+
+// WithActor attach operation actor (e.g. user id) to context
+// actor recorded to audit records of operations called with returned context
+func WithActor(ctx context.Context, actor any) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorOf get actor attached to context (nil if not attached)
+func ActorOf(ctx context.Context) any {
+	return ctx.Value(actorKey{})
+}
+
+// AuditHistory get audit records of document sorted by date (newest first)
+// records of MultiTenant models filtered by operation tenant
+//
+// @param ctx operation context
+// @param id document id
+// @param skip (ignored on 0)
+// @param limit (ignored on 0)
+// @opts operation option
+func AuditHistoryCtx[T any](
+	ctx context.Context,
+	id primitive.ObjectID,
+	skip int64,
+	limit int64,
+	opts ...MongoOption,
+) ([]AuditRecord, error) {
+	res := make([]AuditRecord, 0)
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return res, err
+	}
+	aud, ok := parseAsInterface[Auditable](model)
+	if !ok {
+		return res, nil
+	}
+	filter := primitive.M{"type": model.TypeName(), "document_id": id}
+	if _, tenant, ok, err := tenantOf(model, opt); err != nil {
+		return res, err
+	} else if ok {
+		filter["tenant"] = tenant
+	}
+	sorts := primitive.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	if cur, err := aud.AuditCollection(opt.Database).Find(ctx, filter, FindOption(sorts, skip, limit)); err != nil {
+		return res, err
+	} else {
+		defer cur.Close(ctx)
+		if err := cur.All(ctx, &res); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+func AuditHistory[T any](id primitive.ObjectID, skip int64, limit int64, opts ...MongoOption) ([]AuditRecord, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return AuditHistoryCtx[T](ctx, id, skip, limit, opts...)
+}
+
+// auditOf record model change to audit collection if model is auditable
+func auditOf(ctx context.Context, model Model, action string, id primitive.ObjectID, changes ChangeSet, opt MongoOption) error {
+	aud, ok := parseAsInterface[Auditable](model)
+	if !ok {
+		return nil
+	}
+	_, err := aud.AuditCollection(opt.Database).InsertOne(ctx, recordOf(ctx, model, action, id, changes, opt))
+	return err
+}
+
+// auditInsert record inserted model
+func auditInsert(ctx context.Context, model Model, opt MongoOption) error {
+	if _, ok := parseAsInterface[Auditable](model); !ok {
+		return nil
+	}
+	changes, err := Diff(nil, model)
+	if err != nil {
+		return err
+	}
+	return auditOf(ctx, model, OpInsert, model.GetID(), changes, opt)
+}
+
+// auditSnapshot load stored document of auditable model before delete
+func auditSnapshot(ctx context.Context, model Model, opt MongoOption) (primitive.M, error) {
+	if _, ok := parseAsInterface[Auditable](model); !ok {
+		return nil, nil
+	}
+	res := primitive.M{}
+	if err := model.Collection(opt.Database).FindOne(ctx, primitive.M{"_id": model.GetID()}).Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// auditDelete record deleted model snapshot
+func auditDelete(ctx context.Context, model Model, action string, snapshot primitive.M, opt MongoOption) error {
+	if _, ok := parseAsInterface[Auditable](model); !ok {
+		return nil
+	}
+	changes, err := Diff(snapshot, nil)
+	if err != nil {
+		return err
+	}
+	return auditOf(ctx, model, action, model.GetID(), changes, opt)
+}
+
+// batchAudit matched records of batch operation on auditable model
+// filter level audit recorded if matched records exceed audit batch limit
+type batchAudit struct {
+	model     Model
+	condition any
+	ids       []any
+	olds      map[primitive.ObjectID]primitive.M
+}
+
+// beginBatchAudit load records matched by condition before batch operation
+// returned condition restrict batch operation to loaded records
+func beginBatchAudit(ctx context.Context, model Model, condition any, opt MongoOption) (*batchAudit, any, error) {
+	if _, ok := parseAsInterface[Auditable](model); !ok {
+		return nil, condition, nil
+	}
+	res := &batchAudit{model: model, condition: condition, ids: make([]any, 0)}
+	if auditBatchLimit == 0 {
+		return res, condition, nil
+	}
+	olds, err := loadDocs(ctx, model, filterOf(condition), auditBatchLimit+1, opt)
+	if err != nil {
+		return nil, condition, err
+	}
+	if int64(len(olds)) > auditBatchLimit {
+		return res, condition, nil
+	}
+	for id := range olds {
+		res.ids = append(res.ids, id)
+	}
+	res.olds = olds
+	return res, andCondition(condition, In("_id", res.ids...)), nil
+}
+
+// commit load records after batch operation and record changes
+func (ba *batchAudit) commit(ctx context.Context, action string, update any, opt MongoOption) error {
+	if ba == nil {
+		return nil
+	}
+	aud, _ := parseAsInterface[Auditable](ba.model)
+	if ba.olds == nil {
+		record := recordOf(ctx, ba.model, action, primitive.NilObjectID, ChangeSet{}, opt)
+		record.Filter = filterOf(ba.condition)
+		record.Update = update
+		_, err := aud.AuditCollection(opt.Database).InsertOne(ctx, record)
+		return err
+	}
+	if len(ba.ids) == 0 {
+		return nil
+	}
+	news, err := loadDocs(ctx, ba.model, In("_id", ba.ids...), 0, opt)
+	if err != nil {
+		return err
+	}
+	records := make([]any, 0)
+	for id, old := range ba.olds {
+		changes, err := Diff(old, news[id])
+		if err != nil {
+			return err
+		}
+		if !changes.IsEmpty() {
+			records = append(records, recordOf(ctx, ba.model, action, id, changes, opt))
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	_, err = aud.AuditCollection(opt.Database).InsertMany(ctx, records)
+	return err
+}
+
+// auditModify record change of find one and modify operation of auditable model
+// before must be full document matched by filter loaded in same transaction before write
+// modified document loaded by id (or by filter if inserted on upsert mode) in same transaction
+func auditModify(
+	ctx context.Context,
+	action string,
+	model Model,
+	before primitive.M,
+	filter any,
+	fo FindOneAndOption,
+	deleted bool,
+	opt MongoOption,
+) error {
+	if before == nil && (!fo.Upsert || deleted) {
+		return nil
+	}
+	var after primitive.M
+	if !deleted {
+		var err error
+		if after, err = matchedDoc(ctx, model, filter, fo.Sort, opt); err != nil {
+			return err
+		}
+	}
+	if before == nil {
+		action = OpInsert
+	}
+	changes, err := Diff(before, after)
+	if err != nil || changes.IsEmpty() {
+		return err
+	}
+	id, _ := before["_id"].(primitive.ObjectID)
+	if before == nil {
+		id, _ = after["_id"].(primitive.ObjectID)
+	}
+	return auditOf(ctx, model, action, id, changes, opt)
+}
+
+// decodeDoc decode document map to model
+func decodeDoc(doc primitive.M, v any) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, v)
+}
+
+// loadDocs load raw records of model collection matched by filter
+// limit ignored on 0
+func loadDocs(ctx context.Context, model Model, filter any, limit int64, opt MongoOption) (map[primitive.ObjectID]primitive.M, error) {
+	res := make(map[primitive.ObjectID]primitive.M)
+	option := options.Find()
+	if limit > 0 {
+		option.SetLimit(limit)
+	}
+	cur, err := model.Collection(opt.Database).Find(ctx, filter, option)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		doc := primitive.M{}
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if id, ok := doc["_id"].(primitive.ObjectID); ok {
+			res[id] = doc
+		}
+	}
+	return res, cur.Err()
+}
+
+// recordOf generate audit record of changes
+func recordOf(ctx context.Context, model Model, action string, id primitive.ObjectID, changes ChangeSet, opt MongoOption) AuditRecord {
+	_, tenant, _, _ := tenantOf(model, opt)
+	return AuditRecord{
+		Action:     action,
+		TypeName:   model.TypeName(),
+		Collection: model.Collection(opt.Database).Name(),
+		DocumentID: id,
+		Actor:      ActorOf(ctx),
+		Tenant:     tenant,
+		Changes:    auditChangesOf(changes),
+		CreatedAt:  time.Now().UTC(),
+	}
+}
+
+// auditChangesOf convert change set to sorted audit changes
+func auditChangesOf(changes ChangeSet) []AuditChange {
+	res := make([]AuditChange, 0, len(changes.Set)+len(changes.Unset))
+	for path, after := range changes.Set {
+		res = append(res, AuditChange{Path: path, Before: changes.Old[path], After: after})
+	}
+	for _, path := range changes.Unset {
+		res = append(res, AuditChange{Path: path, Before: changes.Old[path]})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res
+}
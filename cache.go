@@ -0,0 +1,233 @@
+package mongoutils
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type pendingKey struct{}
+
+// Cache read cache backend of FindOne by id
+// values are raw bson documents
+type Cache interface {
+	// Get get cached value of collection key
+	Get(ctx context.Context, collection string, key string) ([]byte, bool)
+	// Set cache value of collection key
+	Set(ctx context.Context, collection string, key string, value []byte)
+	// Invalidate remove all cached values of collection
+	Invalidate(ctx context.Context, collection string)
+}
+
+// globalCache read cache used if no cache passed to option
+var globalCache Cache
+
+// SetCache set global repository read cache
+// pass nil to disable cache
+func SetCache(cache Cache) {
+	globalCache = cache
+}
+
+// cacheOf resolve operation cache (nil if cache disabled)
+func cacheOf(opt MongoOption) Cache {
+	if opt.Cache != nil {
+		return opt.Cache
+	}
+	return globalCache
+}
+
+// cacheCollectionOf get database qualified collection name of model
+func cacheCollectionOf(model Model, opt MongoOption) string {
+	coll := model.Collection(opt.Database)
+	return coll.Database().Name() + "." + coll.Name()
+}
+
+// cacheKeyOf get cache key of FindOne effective filter and pipeline (after middlewares)
+// only filters with single _id ObjectID condition are cacheable
+// key contains pipeline signature, params, trash state and tenant scope to separate different views of record
+func cacheKeyOf(filter any, pipeline MongoPipeline, opt MongoOption) (string, bool) {
+	var id any
+	switch f := filter.(type) {
+	case primitive.M:
+		if len(f) != 1 {
+			return "", false
+		}
+		id = f["_id"]
+	case map[string]any:
+		if len(f) != 1 {
+			return "", false
+		}
+		id = f["_id"]
+	case primitive.D:
+		if len(f) != 1 || f[0].Key != "_id" {
+			return "", false
+		}
+		id = f[0].Value
+	}
+	oid, ok := id.(primitive.ObjectID)
+	if !ok || oid.IsZero() {
+		return "", false
+	}
+	// pipeline stages may changed by middlewares (e.g. user or role scoped match)
+	var sign string
+	if pipeline != nil {
+		var err error
+		if sign, err = cursorFilterSign(pipeline.Build()); err != nil {
+			return "", false
+		}
+	}
+	return fmt.Sprintf(
+		"%s:%v:%t:%t:%t:%s:%s:%s",
+		opt.Pipeline, opt.Params, opt.WithTrashed, opt.OnlyTrashed, opt.IgnoreTenant, opt.Tenant, sign, oid.Hex(),
+	), true
+}
+
+// cacheGenerations local invalidation counter of collections
+var cacheGenerations sync.Map
+
+// cacheGeneration get local invalidation counter of collection
+func cacheGeneration(collection string) *atomic.Uint64 {
+	v, _ := cacheGenerations.LoadOrStore(collection, new(atomic.Uint64))
+	return v.(*atomic.Uint64)
+}
+
+// cacheRecord cache record loaded while collection generation was gen
+// record removed if collection invalidated during load or set, to prevent caching stale record
+// only invalidations of current process detected, stale record may cached until ttl on shared caches
+func cacheRecord(ctx context.Context, cache Cache, collection string, key string, gen uint64, value []byte) {
+	if cacheGeneration(collection).Load() != gen {
+		return
+	}
+	cache.Set(ctx, collection, key, value)
+	if cacheGeneration(collection).Load() != gen {
+		cache.Invalidate(ctx, collection)
+	}
+}
+
+// clearCache increase collection generation and remove cached records
+func clearCache(ctx context.Context, cache Cache, collection string) {
+	cacheGeneration(collection).Add(1)
+	cache.Invalidate(ctx, collection)
+}
+
+// invalidateCache remove cached records of model collection
+// invalidation repeated after transaction end, because record may cached by other operations during transaction
+func invalidateCache(ctx context.Context, model Model, opt MongoOption) {
+	cache := cacheOf(opt)
+	if cache == nil {
+		return
+	}
+	collection := cacheCollectionOf(model, opt)
+	clearCache(ctx, cache, collection)
+	if pending, ok := ctx.Value(pendingKey{}).(*pendingInvalidation); ok {
+		pending.add(cache, collection)
+	}
+}
+
+// pendingInvalidation collections to invalidate after transaction end
+type pendingInvalidation struct {
+	mutex   sync.Mutex
+	entries []pendingEntry
+}
+
+type pendingEntry struct {
+	cache      Cache
+	collection string
+}
+
+func (pi *pendingInvalidation) add(cache Cache, collection string) {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
+	pi.entries = append(pi.entries, pendingEntry{cache: cache, collection: collection})
+}
+
+func (pi *pendingInvalidation) flush(ctx context.Context) {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
+	for _, e := range pi.entries {
+		clearCache(ctx, e.cache, e.collection)
+	}
+	pi.entries = nil
+}
+
+// memoryCache in memory LRU cache with TTL
+type memoryCache struct {
+	mutex       sync.Mutex
+	size        int
+	ttl         time.Duration
+	order       *list.List
+	entries     map[string]*list.Element
+	collections map[string]map[string]*list.Element
+}
+
+type memoryEntry struct {
+	collection string
+	key        string
+	value      []byte
+	expires    time.Time
+}
+
+func (mc *memoryCache) Get(ctx context.Context, collection string, key string) ([]byte, bool) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	el, ok := mc.entries[collection+"/"+key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if mc.ttl > 0 && time.Now().After(entry.expires) {
+		mc.remove(el)
+		return nil, false
+	}
+	mc.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (mc *memoryCache) Set(ctx context.Context, collection string, key string, value []byte) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	id := collection + "/" + key
+	if el, ok := mc.entries[id]; ok {
+		mc.remove(el)
+	}
+	el := mc.order.PushFront(&memoryEntry{
+		collection: collection,
+		key:        id,
+		value:      value,
+		expires:    time.Now().Add(mc.ttl),
+	})
+	mc.entries[id] = el
+	if _, ok := mc.collections[collection]; !ok {
+		mc.collections[collection] = make(map[string]*list.Element)
+	}
+	mc.collections[collection][id] = el
+	for mc.size > 0 && mc.order.Len() > mc.size {
+		mc.remove(mc.order.Back())
+	}
+}
+
+func (mc *memoryCache) Invalidate(ctx context.Context, collection string) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	for _, el := range mc.collections[collection] {
+		mc.remove(el)
+	}
+}
+
+// remove remove element from cache (lock must held by caller)
+func (mc *memoryCache) remove(el *list.Element) {
+	entry := el.Value.(*memoryEntry)
+	mc.order.Remove(el)
+	delete(mc.entries, entry.key)
+	if keys, ok := mc.collections[entry.collection]; ok {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(mc.collections, entry.collection)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package mongoutils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.TODO()
+	cache := mongoutils.NewMemoryCache(2, time.Minute)
+	cache.Set(ctx, "test.users", "a", []byte("a"))
+	cache.Set(ctx, "test.users", "b", []byte("b"))
+	cache.Get(ctx, "test.users", "a")
+	cache.Set(ctx, "test.cities", "c", []byte("c"))
+	if _, ok := cache.Get(ctx, "test.users", "b"); ok {
+		t.Fatal("least recently used record not evicted")
+	}
+	if v, ok := cache.Get(ctx, "test.users", "a"); !ok || string(v) != "a" {
+		t.Fatal("recently used record evicted")
+	}
+
+	cache.Invalidate(ctx, "test.users")
+	if _, ok := cache.Get(ctx, "test.users", "a"); ok {
+		t.Fatal("collection not invalidated")
+	}
+	if _, ok := cache.Get(ctx, "test.cities", "c"); !ok {
+		t.Fatal("other collection invalidated")
+	}
+
+	expiring := mongoutils.NewMemoryCache(0, time.Millisecond)
+	expiring.Set(ctx, "test.users", "a", []byte("a"))
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := expiring.Get(ctx, "test.users", "a"); ok {
+		t.Fatal("expired record returned")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	id := primitive.NewObjectID()
+	tenant, _ := mongoutils.CacheKeyOf(primitive.M{"_id": id}, nil, mongoutils.MongoOption{Tenant: "a"})
+	ignored, _ := mongoutils.CacheKeyOf(primitive.M{"_id": id}, nil, mongoutils.MongoOption{Tenant: "a", IgnoreTenant: true})
+	if tenant == ignored {
+		t.Fatal("tenant scope not separated")
+	}
+	if _, ok := mongoutils.CacheKeyOf(primitive.M{"_id": id, "name": "John"}, nil, mongoutils.MongoOption{}); ok {
+		t.Fatal("non id filter cached")
+	}
+	if _, ok := mongoutils.CacheKeyOf(primitive.D{{Key: "_id", Value: id}}, nil, mongoutils.MongoOption{}); !ok {
+		t.Fatal("id filter not cached")
+	}
+
+	// pipeline narrowed by middleware must separate records
+	admin, _ := mongoutils.CacheKeyOf(primitive.M{"_id": id}, mongoutils.NewPipe(), mongoutils.MongoOption{})
+	user, _ := mongoutils.CacheKeyOf(primitive.M{"_id": id}, mongoutils.NewPipe().Match(primitive.M{"owner": "john"}), mongoutils.MongoOption{})
+	same, _ := mongoutils.CacheKeyOf(primitive.M{"_id": id}, mongoutils.NewPipe().Match(primitive.M{"owner": "john"}), mongoutils.MongoOption{})
+	if admin == user || user != same {
+		t.Fatal("pipeline not separated")
+	}
+}
+
+func TestCacheStaleRecord(t *testing.T) {
+	ctx := context.TODO()
+	cache := mongoutils.NewMemoryCache(0, 0)
+
+	gen := mongoutils.CacheGenerationOf("test.stale")
+	mongoutils.CacheRecord(ctx, cache, "test.stale", "a", gen, []byte("a"))
+	if _, ok := cache.Get(ctx, "test.stale", "a"); !ok {
+		t.Fatal("record not cached")
+	}
+
+	// collection invalidated during load
+	gen = mongoutils.CacheGenerationOf("test.stale")
+	mongoutils.ClearCache(ctx, cache, "test.stale")
+	mongoutils.CacheRecord(ctx, cache, "test.stale", "a", gen, []byte("stale"))
+	if _, ok := cache.Get(ctx, "test.stale", "a"); ok {
+		t.Fatal("stale record cached")
+	}
+}
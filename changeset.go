@@ -0,0 +1,168 @@
+package mongoutils
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type changesKey struct{}
+
+// ChangeSet changed paths of document
+type ChangeSet struct {
+	// Set changed or added paths new value
+	Set primitive.M
+	// Unset removed paths
+	Unset []string
+	// Old changed or removed paths old value
+	Old primitive.M
+}
+
+// Diff compute changed paths between old and new document
+// nested documents compared field by field and arrays with same length compared item by item
+// _id field ignored
+func Diff(old any, new any) (ChangeSet, error) {
+	res := ChangeSet{Set: primitive.M{}, Unset: make([]string, 0), Old: primitive.M{}}
+	oldDoc, err := toDocMap(old)
+	if err != nil {
+		return res, err
+	}
+	newDoc, err := toDocMap(new)
+	if err != nil {
+		return res, err
+	}
+	delete(oldDoc, "_id")
+	delete(newDoc, "_id")
+	res.diff("", oldDoc, newDoc)
+	sort.Strings(res.Unset)
+	return res, nil
+}
+
+// ChangesOf get ChangeSet passed to OnUpdated hook context by repository Update function
+func ChangesOf(ctx context.Context) (ChangeSet, bool) {
+	v, ok := ctx.Value(changesKey{}).(ChangeSet)
+	return v, ok
+}
+
+// IsEmpty check if no path changed
+func (cs ChangeSet) IsEmpty() bool {
+	return len(cs.Set) == 0 && len(cs.Unset) == 0
+}
+
+// Has check if path or one of its nested paths changed
+func (cs ChangeSet) Has(path string) bool {
+	for _, p := range cs.Paths() {
+		if p == path || strings.HasPrefix(p, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Paths get sorted list of changed paths
+func (cs ChangeSet) Paths() []string {
+	res := make([]string, 0, len(cs.Set)+len(cs.Unset))
+	for k := range cs.Set {
+		res = append(res, k)
+	}
+	res = append(res, cs.Unset...)
+	sort.Strings(res)
+	return res
+}
+
+// Update generate mongo update document with $set and $unset operators
+// returns empty map if no path changed
+func (cs ChangeSet) Update() primitive.M {
+	res := primitive.M{}
+	if len(cs.Set) > 0 {
+		res["$set"] = cs.Set
+	}
+	if len(cs.Unset) > 0 {
+		unset := primitive.M{}
+		for _, k := range cs.Unset {
+			unset[k] = ""
+		}
+		res["$unset"] = unset
+	}
+	return res
+}
+
+func (cs *ChangeSet) diff(prefix string, old, new primitive.M) {
+	for k, nv := range new {
+		path := keyOf(prefix, k)
+		if ov, ok := old[k]; !ok {
+			cs.Set[path] = nv
+		} else {
+			cs.diffValue(path, ov, nv)
+		}
+	}
+	for k, ov := range old {
+		if _, ok := new[k]; !ok {
+			cs.Unset = append(cs.Unset, keyOf(prefix, k))
+			cs.Old[keyOf(prefix, k)] = ov
+		}
+	}
+}
+
+func (cs *ChangeSet) diffValue(path string, ov, nv any) {
+	oDoc, oIsDoc := asDocMap(ov)
+	nDoc, nIsDoc := asDocMap(nv)
+	if oIsDoc && nIsDoc {
+		cs.diff(path, oDoc, nDoc)
+		return
+	}
+	oArr, oIsArr := ov.(primitive.A)
+	nArr, nIsArr := nv.(primitive.A)
+	if oIsArr && nIsArr && len(oArr) == len(nArr) {
+		for i := range nArr {
+			cs.diffValue(keyOf(path, strconv.Itoa(i)), oArr[i], nArr[i])
+		}
+		return
+	}
+	if !reflect.DeepEqual(ov, nv) {
+		cs.Set[path] = nv
+		cs.Old[path] = ov
+	}
+}
+
+// toDocMap convert value to primitive.M using bson encoding
+func toDocMap(v any) (primitive.M, error) {
+	res := primitive.M{}
+	if v == nil {
+		return res, nil
+	}
+	if val := reflect.ValueOf(v); val.Kind() == reflect.Pointer && val.IsNil() {
+		return res, nil
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// asDocMap convert nested document to primitive.M
+func asDocMap(v any) (primitive.M, bool) {
+	switch d := v.(type) {
+	case primitive.M:
+		return d, true
+	case primitive.D:
+		return d.Map(), true
+	}
+	return nil, false
+}
+
+func keyOf(root, key string) string {
+	if root == "" {
+		return key
+	}
+	return root + "." + key
+}
@@ -0,0 +1,66 @@
+package mongoutils_test
+
+import (
+	"testing"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type diffAddress struct {
+	City   string `bson:"city"`
+	Street string `bson:"street"`
+}
+
+type diffPerson struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Name    string             `bson:"name"`
+	Nick    string             `bson:"nick,omitempty"`
+	Address diffAddress        `bson:"address"`
+	Skills  []string           `bson:"skills"`
+	Tags    []string           `bson:"tags"`
+}
+
+func TestDiff(t *testing.T) {
+	old := diffPerson{
+		ID:      primitive.NewObjectID(),
+		Name:    "John",
+		Nick:    "JJ",
+		Address: diffAddress{City: "London", Street: "12th"},
+		Skills:  []string{"go", "js"},
+		Tags:    []string{"a"},
+	}
+	new := old
+	new.ID = primitive.NewObjectID()
+	new.Nick = ""
+	new.Address.Street = "13th"
+	new.Skills = []string{"go", "mongo"}
+	new.Tags = []string{"a", "b"}
+
+	changes, err := mongoutils.Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := pretty(changes.Update())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `{"$set":{"address.street":"13th","skills.1":"mongo","tags":["a","b"]},"$unset":{"nick":""}}` {
+		t.Log(v)
+		t.Fatal("fail Update")
+	}
+
+	if !changes.Has("address") || changes.Has("name") {
+		t.Fatal("fail Has")
+	}
+
+	if changes.Old["address.street"] != "12th" || changes.Old["nick"] != "JJ" {
+		t.Log(changes.Old)
+		t.Fatal("fail Old")
+	}
+
+	if changes, _ := mongoutils.Diff(old, old); !changes.IsEmpty() {
+		t.Fatal("fail IsEmpty")
+	}
+}
@@ -0,0 +1,137 @@
+package mongoutils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound returned when no record found
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicateKey returned (as DuplicateKeyError) on unique index violation
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrNotEditable returned when model is not editable
+	ErrNotEditable = errors.New("record is not editable")
+	// ErrNotDeletable returned when model is not deletable
+	ErrNotDeletable = errors.New("record is not deletable")
+	// ErrInvalidPipeline returned when model pipeline method not defined or not return MongoPipeline
+	ErrInvalidPipeline = errors.New("invalid pipeline")
+	// ErrInvalidCursor returned when cursor token is malformed, tampered or generated for other query
+	ErrInvalidCursor = errors.New("invalid cursor token")
+	// ErrConflict returned when revisioned document changed by another operation
+	ErrConflict = errors.New("document revision conflict")
+	// ErrTenantRequired returned when no tenant or tenant database resolved for operation
+	ErrTenantRequired = errors.New("tenant required")
+	// ErrTenantMismatch returned when model belongs to other tenant
+	ErrTenantMismatch = errors.New("tenant mismatch")
+	// ErrPreImageRequired returned when tenant of watched delete event can not resolved without pre-image
+	ErrPreImageRequired = errors.New("change stream pre-image required")
+)
+
+var duplicateIndexRx = regexp.MustCompile(`index: (\S+) dup key`)
+
+// DuplicateKeyError unique index violation error
+// this error matches ErrDuplicateKey with errors.Is
+type DuplicateKeyError struct {
+	// Index violated index name
+	Index string
+	// Keys duplicated key values
+	Keys primitive.M
+	// Err original mongo error
+	Err error
+}
+
+func (e DuplicateKeyError) Error() string {
+	return fmt.Sprintf("%s: index %s %v", ErrDuplicateKey.Error(), e.Index, e.Keys)
+}
+
+func (e DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+func (e DuplicateKeyError) Unwrap() error {
+	return e.Err
+}
+
+// ConflictError revision conflict error
+// this error matches ErrConflict with errors.Is
+type ConflictError struct {
+	ID       primitive.ObjectID
+	Revision int64
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s revision %d", ErrConflict.Error(), e.ID.Hex(), e.Revision)
+}
+
+func (e ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// HookError model post hook error
+// write done (or rolled back on HookTx mode) and Result contains operation write result
+type HookError struct {
+	Hook   string
+	Result any
+	Err    error
+}
+
+func (e HookError) Error() string {
+	return e.Hook + " hook failed: " + e.Err.Error()
+}
+
+func (e HookError) Unwrap() error {
+	return e.Err
+}
+
+// conflictOf generate conflict error for model
+func conflictOf(model Model) error {
+	err := ConflictError{ID: model.GetID()}
+	if rev, ok := parseAsInterface[RevisionVersioning](model); ok {
+		err.Revision = rev.GetRevision()
+	}
+	return err
+}
+
+// parseError convert mongo duplicate key errors to DuplicateKeyError
+func parseError(err error) error {
+	if err == nil || !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	var we mongo.WriteException
+	var bwe mongo.BulkWriteException
+	var ce mongo.CommandError
+	switch {
+	case errors.As(err, &we) && len(we.WriteErrors) > 0:
+		return duplicateKeyOf(we.WriteErrors[0].Raw, we.WriteErrors[0].Message, err)
+	case errors.As(err, &bwe) && len(bwe.WriteErrors) > 0:
+		return duplicateKeyOf(bwe.WriteErrors[0].Raw, bwe.WriteErrors[0].Message, err)
+	case errors.As(err, &ce):
+		return duplicateKeyOf(ce.Raw, ce.Message, err)
+	}
+	return duplicateKeyOf(nil, err.Error(), err)
+}
+
+// writeErrorOf convert duplicate key write error to DuplicateKeyError
+func writeErrorOf(we mongo.WriteError) error {
+	if we.Code == 11000 || we.Code == 11001 || we.Code == 12582 {
+		return duplicateKeyOf(we.Raw, we.Message, we)
+	}
+	return we
+}
+
+func duplicateKeyOf(raw bson.Raw, message string, err error) DuplicateKeyError {
+	res := DuplicateKeyError{Keys: primitive.M{}, Err: err}
+	if m := duplicateIndexRx.FindStringSubmatch(message); len(m) > 1 {
+		res.Index = m[1]
+	}
+	if v, e := raw.LookupErr("keyValue"); e == nil {
+		v.Unmarshal(&res.Keys)
+	}
+	return res
+}
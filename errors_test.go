@@ -0,0 +1,122 @@
+package mongoutils_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const dupMessage = `E11000 duplicate key error collection: test.users index: email_1 dup key: { email: "john@example.com" }`
+
+func dupRaw(t *testing.T) bson.Raw {
+	raw, err := bson.Marshal(bson.M{"keyValue": bson.M{"email": "john@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestParseError(t *testing.T) {
+	raw := dupRaw(t)
+	other := errors.New("network error")
+	cases := []struct {
+		name  string
+		err   error
+		index string
+		keys  primitive.M
+	}{
+		{"nil", nil, "", nil},
+		{"not duplicate", other, "", nil},
+		{"not duplicate command", mongo.CommandError{Code: 50, Message: "exceeded time limit"}, "", nil},
+		{
+			name: "write exception",
+			err: mongo.WriteException{WriteErrors: mongo.WriteErrors{
+				{Index: 0, Code: 11000, Message: dupMessage, Raw: raw},
+			}},
+			index: "email_1",
+			keys:  primitive.M{"email": "john@example.com"},
+		},
+		{
+			name: "bulk write exception",
+			err: mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+				{WriteError: mongo.WriteError{Index: 2, Code: 11000, Message: dupMessage, Raw: raw}},
+			}},
+			index: "email_1",
+			keys:  primitive.M{"email": "john@example.com"},
+		},
+		{
+			name:  "command error",
+			err:   mongo.CommandError{Code: 11000, Message: dupMessage, Raw: raw},
+			index: "email_1",
+			keys:  primitive.M{"email": "john@example.com"},
+		},
+		{
+			name:  "command error without key value",
+			err:   mongo.CommandError{Code: 11000, Message: dupMessage},
+			index: "email_1",
+			keys:  primitive.M{},
+		},
+	}
+	for _, c := range cases {
+		err := mongoutils.ParseError(c.err)
+		if c.keys == nil {
+			if !reflect.DeepEqual(err, c.err) {
+				t.Errorf("%s: error changed %v", c.name, err)
+			}
+			continue
+		}
+		var dup mongoutils.DuplicateKeyError
+		if !errors.As(err, &dup) || !errors.Is(err, mongoutils.ErrDuplicateKey) {
+			t.Errorf("%s: not duplicate key error %v", c.name, err)
+			continue
+		}
+		if dup.Index != c.index || !reflect.DeepEqual(dup.Keys, c.keys) {
+			t.Errorf("%s: expected %s %v, got %s %v", c.name, c.index, c.keys, dup.Index, dup.Keys)
+		}
+		if !reflect.DeepEqual(dup.Err, c.err) {
+			t.Errorf("%s: original error not wrapped", c.name)
+		}
+	}
+}
+
+func TestWriteErrorOf(t *testing.T) {
+	raw := dupRaw(t)
+	cases := []struct {
+		name string
+		we   mongo.WriteError
+		dup  bool
+	}{
+		{"duplicate key", mongo.WriteError{Code: 11000, Message: dupMessage, Raw: raw}, true},
+		{"legacy duplicate key", mongo.WriteError{Code: 11001, Message: dupMessage, Raw: raw}, true},
+		{"validation", mongo.WriteError{Code: 121, Message: "document failed validation"}, false},
+	}
+	for _, c := range cases {
+		err := mongoutils.WriteErrorOf(c.we)
+		if errors.Is(err, mongoutils.ErrDuplicateKey) != c.dup {
+			t.Errorf("%s: expected duplicate %v, got %v", c.name, c.dup, err)
+			continue
+		}
+		var we mongo.WriteError
+		if !errors.As(err, &we) || we.Code != c.we.Code {
+			t.Errorf("%s: write error not wrapped", c.name)
+		}
+	}
+}
+
+func TestDuplicateKeyError(t *testing.T) {
+	err := mongoutils.DuplicateKeyError{Index: "email_1", Keys: primitive.M{"email": "john@example.com"}}
+	if !errors.Is(err, mongoutils.ErrDuplicateKey) {
+		t.Error("DuplicateKeyError must match ErrDuplicateKey")
+	}
+	if errors.Is(err, mongoutils.ErrConflict) {
+		t.Error("DuplicateKeyError must not match ErrConflict")
+	}
+	if v := err.Error(); v != "duplicate key: index email_1 map[email:john@example.com]" {
+		t.Errorf("unexpected message %s", v)
+	}
+}
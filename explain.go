@@ -0,0 +1,273 @@
+package mongoutils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ExplainSummary parsed explain result of aggregate pipeline
+type ExplainSummary struct {
+	// WinningPlan winning plan stages from root to leaf (e.g. LIMIT > FETCH > IXSCAN)
+	WinningPlan string
+	// Indexes used indexes name
+	Indexes []string
+	// DocsExamined examined documents count
+	DocsExamined int64
+	// KeysExamined examined index keys count
+	KeysExamined int64
+	// DocsReturned returned documents count
+	DocsReturned int64
+	// CollectionScan plan contains collection scan (COLLSCAN)
+	CollectionScan bool
+	// InMemorySort documents sorted in memory (SORT stage or $sort pipeline stage)
+	InMemorySort bool
+	// ExecutionTime server execution time
+	ExecutionTime time.Duration
+	// Raw explain command result
+	Raw bson.Raw
+}
+
+// Warnings get plan warnings (collection scan and in memory sort)
+func (es *ExplainSummary) Warnings() []string {
+	res := make([]string, 0)
+	if es == nil {
+		return res
+	}
+	if es.CollectionScan {
+		res = append(res, fmt.Sprintf("collection scan (COLLSCAN), %d docs examined for %d returned", es.DocsExamined, es.DocsReturned))
+	}
+	if es.InMemorySort {
+		res = append(res, "in memory sort, no index used for sort")
+	}
+	return res
+}
+
+// Explain explain find pipeline without returning records
+// model pipeline with filter, sorts, skip and limit explained with executionStats verbosity
+// pipeline executed on server to collect execution stats
+// explain not allowed inside transaction
+//
+// @param ctx operation context
+// @param filter (ignored on nil)
+// @param sorts (ignored on nil)
+// @param skip (ignored on 0)
+// @param limit (ignored on 0)
+// @opts operation option
+func ExplainCtx[T any](
+	ctx context.Context,
+	filter any,
+	sorts any,
+	skip int64,
+	limit int64,
+	opts ...MongoOption,
+) (*ExplainSummary, error) {
+	model := modelSafe(new(T))
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := modelPipeline(model, opt)
+	if err != nil {
+		return nil, err
+	}
+	op := &Operation{Name: OpExplain, Model: model, Filter: filter, Pipeline: pipeline, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (*ExplainSummary, error) {
+		pipe := op.Pipeline.
+			Match(op.Filter).
+			Sort(sorts).
+			Skip(skip).
+			Limit(limit).
+			Build()
+		return explainPipeline(ctx, collectionOf(model, opt), pipe, "executionStats", opt)
+	})
+}
+func Explain[T any](filter any, sorts any, skip int64, limit int64, opts ...MongoOption) (*ExplainSummary, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return ExplainCtx[T](ctx, filter, sorts, skip, limit, opts...)
+}
+
+// explainPipeline run explain command on aggregate pipeline with verbosity
+// executionStats verbosity execute pipeline on server, queryPlanner only select plan
+func explainPipeline(ctx context.Context, coll *mongo.Collection, pipe mongo.Pipeline, verbosity string, opt MongoOption) (*ExplainSummary, error) {
+	raw, err := coll.Database().RunCommand(ctx, explainCommand(coll.Name(), pipe, verbosity, opt)).DecodeBytes()
+	if err != nil {
+		return nil, err
+	}
+	return parseExplain(raw)
+}
+
+// explainCommand generate explain command of aggregate pipeline
+// hint, collation, allowDiskUse and comment copied from aggregate options to explain same query plan
+func explainCommand(collection string, pipe mongo.Pipeline, verbosity string, opt MongoOption) bson.D {
+	aggregate := bson.D{
+		{Key: "aggregate", Value: collection},
+		{Key: "pipeline", Value: pipe},
+		{Key: "cursor", Value: bson.D{}},
+	}
+	option := aggregateOptionOf(opt)
+	if option.AllowDiskUse != nil {
+		aggregate = append(aggregate, bson.E{Key: "allowDiskUse", Value: *option.AllowDiskUse})
+	}
+	if option.Hint != nil {
+		aggregate = append(aggregate, bson.E{Key: "hint", Value: option.Hint})
+	}
+	if option.Collation != nil {
+		aggregate = append(aggregate, bson.E{Key: "collation", Value: option.Collation.ToDocument()})
+	}
+	if option.Comment != nil {
+		aggregate = append(aggregate, bson.E{Key: "comment", Value: *option.Comment})
+	}
+	return bson.D{
+		{Key: "explain", Value: aggregate},
+		{Key: "verbosity", Value: verbosity},
+	}
+}
+
+// parseExplain parse explain command result
+// supports find layer result, aggregate stages result and sharded result
+// shards parsed in shard name order and counts summed
+func parseExplain(raw bson.Raw) (*ExplainSummary, error) {
+	doc := primitive.M{}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	res := &ExplainSummary{Raw: raw, Indexes: make([]string, 0)}
+	res.parse(doc)
+	if shards, ok := asDocMap(doc["shards"]); ok {
+		names := make([]string, 0, len(shards))
+		for name := range shards {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if shard, ok := asDocMap(shards[name]); ok {
+				res.parse(shard)
+			}
+		}
+	}
+	return res, nil
+}
+
+// parse parse explain document or shard explain document
+// returned count of document added to previous documents (shards) count
+func (es *ExplainSummary) parse(doc primitive.M) {
+	returned := es.DocsReturned
+	es.parseCursor(doc)
+	stages, _ := doc["stages"].(primitive.A)
+	for i, stage := range stages {
+		stage, ok := asDocMap(stage)
+		if !ok {
+			continue
+		}
+		if cursor, ok := asDocMap(stage["$cursor"]); ok {
+			es.parseCursor(cursor)
+		}
+		if _, ok := stage["$sort"]; ok {
+			es.InMemorySort = true
+		}
+		// last stage returned count is pipeline result
+		if n, ok := int64Of(stage["nReturned"]); ok && i == len(stages)-1 {
+			es.DocsReturned = returned + n
+		}
+	}
+}
+
+// parseCursor parse query planner and execution stats
+func (es *ExplainSummary) parseCursor(doc primitive.M) {
+	if planner, ok := asDocMap(doc["queryPlanner"]); ok {
+		if plan, ok := asDocMap(planner["winningPlan"]); ok {
+			stages := es.parsePlan(plan, make([]string, 0))
+			if es.WinningPlan != "" && len(stages) > 0 {
+				es.WinningPlan += " | "
+			}
+			es.WinningPlan += strings.Join(stages, " > ")
+		}
+	}
+	if stats, ok := asDocMap(doc["executionStats"]); ok {
+		if n, ok := int64Of(stats["totalDocsExamined"]); ok {
+			es.DocsExamined += n
+		}
+		if n, ok := int64Of(stats["totalKeysExamined"]); ok {
+			es.KeysExamined += n
+		}
+		if n, ok := int64Of(stats["nReturned"]); ok {
+			es.DocsReturned += n
+		}
+		if n, ok := int64Of(stats["executionTimeMillis"]); ok {
+			es.ExecutionTime = max(es.ExecutionTime, time.Duration(n)*time.Millisecond)
+		}
+	}
+}
+
+// parsePlan walk plan tree and collect stages, indexes and flags
+func (es *ExplainSummary) parsePlan(plan primitive.M, stages []string) []string {
+	// slot based engine wrap classic plan in queryPlan
+	if inner, ok := asDocMap(plan["queryPlan"]); ok {
+		return es.parsePlan(inner, stages)
+	}
+	stage, _ := plan["stage"].(string)
+	if stage != "" {
+		stages = append(stages, stage)
+	}
+	switch stage {
+	case "COLLSCAN":
+		es.CollectionScan = true
+	case "SORT":
+		es.InMemorySort = true
+	}
+	if index, ok := plan["indexName"].(string); ok && index != "" {
+		es.Indexes = appendUnique(es.Indexes, index)
+	}
+	if input, ok := asDocMap(plan["inputStage"]); ok {
+		stages = es.parsePlan(input, stages)
+	}
+	if inputs, ok := plan["inputStages"].(primitive.A); ok {
+		for _, input := range inputs {
+			if input, ok := asDocMap(input); ok {
+				stages = es.parsePlan(input, stages)
+			}
+		}
+	}
+	// sharded find layer plan (SHARD_MERGE) contains winning plan of each shard
+	if shards, ok := plan["shards"].(primitive.A); ok {
+		for _, shard := range shards {
+			if shard, ok := asDocMap(shard); ok {
+				if input, ok := asDocMap(shard["winningPlan"]); ok {
+					stages = es.parsePlan(input, stages)
+				}
+			}
+		}
+	}
+	return stages
+}
+
+// int64Of convert bson number to int64
+func int64Of(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// appendUnique append item to slice if not exists
+func appendUnique(items []string, item string) []string {
+	for _, i := range items {
+		if i == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
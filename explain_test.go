@@ -0,0 +1,195 @@
+package mongoutils_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestExplainWarnings(t *testing.T) {
+	var empty *mongoutils.ExplainSummary
+	if len(empty.Warnings()) != 0 {
+		t.Fatal("nil summary must have no warning")
+	}
+	indexed := &mongoutils.ExplainSummary{WinningPlan: "FETCH > IXSCAN", Indexes: []string{"name_1"}}
+	if len(indexed.Warnings()) != 0 {
+		t.Fatalf("unexpected warnings %v", indexed.Warnings())
+	}
+	scan := &mongoutils.ExplainSummary{WinningPlan: "SORT > COLLSCAN", CollectionScan: true, InMemorySort: true, DocsExamined: 1000, DocsReturned: 10}
+	if warnings := scan.Warnings(); len(warnings) != 2 {
+		t.Fatalf("expected collection scan and sort warnings, got %v", warnings)
+	}
+}
+
+func explainOf(t *testing.T, doc bson.D) *mongoutils.ExplainSummary {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := mongoutils.ParseExplain(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestParseExplain(t *testing.T) {
+	ixscan := bson.D{
+		{Key: "stage", Value: "FETCH"},
+		{Key: "inputStage", Value: bson.D{{Key: "stage", Value: "IXSCAN"}, {Key: "indexName", Value: "name_1"}}},
+	}
+	collscan := bson.D{
+		{Key: "stage", Value: "SORT"},
+		{Key: "inputStage", Value: bson.D{{Key: "stage", Value: "COLLSCAN"}}},
+	}
+	cursorOf := func(plan bson.D, examined, returned int32) bson.D {
+		return bson.D{
+			{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: plan}}},
+			{Key: "executionStats", Value: bson.D{
+				{Key: "nReturned", Value: returned},
+				{Key: "totalDocsExamined", Value: examined},
+				{Key: "totalKeysExamined", Value: examined},
+				{Key: "executionTimeMillis", Value: int32(3)},
+			}},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		doc      bson.D
+		expected mongoutils.ExplainSummary
+	}{
+		{
+			name: "classic",
+			doc: cursorOf(bson.D{
+				{Key: "stage", Value: "LIMIT"},
+				{Key: "inputStage", Value: ixscan},
+			}, 10, 10),
+			expected: mongoutils.ExplainSummary{
+				WinningPlan:  "LIMIT > FETCH > IXSCAN",
+				Indexes:      []string{"name_1"},
+				DocsExamined: 10, KeysExamined: 10, DocsReturned: 10,
+				ExecutionTime: 3 * time.Millisecond,
+			},
+		},
+		{
+			name: "slot based engine",
+			doc: cursorOf(bson.D{
+				{Key: "queryPlan", Value: collscan},
+				{Key: "slotBasedPlan", Value: bson.D{{Key: "stages", Value: "[1] scan s1 s2"}}},
+			}, 100, 100),
+			expected: mongoutils.ExplainSummary{
+				WinningPlan:  "SORT > COLLSCAN",
+				Indexes:      []string{},
+				DocsExamined: 100, KeysExamined: 100, DocsReturned: 100,
+				CollectionScan: true, InMemorySort: true,
+				ExecutionTime: 3 * time.Millisecond,
+			},
+		},
+		{
+			name: "cursor stages",
+			doc: bson.D{{Key: "stages", Value: bson.A{
+				bson.D{{Key: "$cursor", Value: cursorOf(ixscan, 50, 50)}, {Key: "nReturned", Value: int64(50)}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "sortKey", Value: bson.D{{Key: "age", Value: 1}}}}}, {Key: "nReturned", Value: int64(50)}},
+				bson.D{{Key: "$limit", Value: int64(5)}, {Key: "nReturned", Value: int64(5)}},
+			}}},
+			expected: mongoutils.ExplainSummary{
+				WinningPlan:  "FETCH > IXSCAN",
+				Indexes:      []string{"name_1"},
+				DocsExamined: 50, KeysExamined: 50, DocsReturned: 5,
+				InMemorySort:  true,
+				ExecutionTime: 3 * time.Millisecond,
+			},
+		},
+		{
+			name: "sharded aggregate",
+			doc: bson.D{
+				{Key: "mergeType", Value: "mongos"},
+				{Key: "shards", Value: bson.D{
+					{Key: "shard1", Value: bson.D{{Key: "stages", Value: bson.A{
+						bson.D{{Key: "$cursor", Value: cursorOf(ixscan, 20, 20)}, {Key: "nReturned", Value: int64(20)}},
+						bson.D{{Key: "$limit", Value: int64(2)}, {Key: "nReturned", Value: int64(2)}},
+					}}}},
+					{Key: "shard0", Value: cursorOf(collscan, 30, 4)},
+				}},
+			},
+			expected: mongoutils.ExplainSummary{
+				WinningPlan:  "SORT > COLLSCAN | FETCH > IXSCAN",
+				Indexes:      []string{"name_1"},
+				DocsExamined: 50, KeysExamined: 50, DocsReturned: 6,
+				CollectionScan: true, InMemorySort: true,
+				ExecutionTime: 3 * time.Millisecond,
+			},
+		},
+		{
+			name: "sharded find",
+			doc: cursorOf(bson.D{
+				{Key: "stage", Value: "SHARD_MERGE"},
+				{Key: "shards", Value: bson.A{
+					bson.D{{Key: "shardName", Value: "shard0"}, {Key: "winningPlan", Value: ixscan}},
+					bson.D{{Key: "shardName", Value: "shard1"}, {Key: "winningPlan", Value: bson.D{{Key: "stage", Value: "COLLSCAN"}}}},
+				}},
+			}, 12, 7),
+			expected: mongoutils.ExplainSummary{
+				WinningPlan:  "SHARD_MERGE > FETCH > IXSCAN > COLLSCAN",
+				Indexes:      []string{"name_1"},
+				DocsExamined: 12, KeysExamined: 12, DocsReturned: 7,
+				CollectionScan: true,
+				ExecutionTime:  3 * time.Millisecond,
+			},
+		},
+	}
+	for _, c := range cases {
+		res := explainOf(t, c.doc)
+		res.Raw = nil
+		if !reflect.DeepEqual(*res, c.expected) {
+			t.Errorf("%s: expected %+v, got %+v", c.name, c.expected, *res)
+		}
+	}
+}
+
+func TestExplainCommand(t *testing.T) {
+	pipe := mongoutils.NewPipe().Match(bson.M{"name": "John"}).Build()
+	data, err := bson.Marshal(mongoutils.ExplainCommand("users", pipe, "executionStats", mongoutils.MongoOption{
+		Hint:      "name_1",
+		Collation: &options.Collation{Locale: "en", Strength: 2},
+		Comment:   "report",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := bson.Raw(data)
+	if v := raw.Lookup("verbosity").StringValue(); v != "executionStats" {
+		t.Errorf("unexpected verbosity %s", v)
+	}
+	cmd := raw.Lookup("explain").Document()
+	if v := cmd.Lookup("aggregate").StringValue(); v != "users" {
+		t.Errorf("unexpected collection %s", v)
+	}
+	if v := cmd.Lookup("hint").StringValue(); v != "name_1" {
+		t.Errorf("hint not passed: %s", v)
+	}
+	if v := cmd.Lookup("collation", "locale").StringValue(); v != "en" {
+		t.Errorf("collation not passed: %s", v)
+	}
+	if v := cmd.Lookup("comment").StringValue(); v != "report" {
+		t.Errorf("comment not passed: %s", v)
+	}
+	if v, ok := cmd.Lookup("allowDiskUse").BooleanOK(); !ok || !v {
+		t.Error("allowDiskUse not passed")
+	}
+
+	// empty option
+	data, _ = bson.Marshal(mongoutils.ExplainCommand("users", pipe, "queryPlanner", mongoutils.MongoOption{}))
+	raw = bson.Raw(data)
+	if v := raw.Lookup("verbosity").StringValue(); v != "queryPlanner" {
+		t.Errorf("unexpected verbosity %s", v)
+	}
+	if _, err := raw.Lookup("explain").Document().LookupErr("hint"); err == nil {
+		t.Error("unexpected hint")
+	}
+}
@@ -0,0 +1,65 @@
+package mongoutils
+
+import (
+	"context"
+	"time"
+)
+
+// export unexported helpers for mongoutils_test package
+var (
+	KeepTimestamps      = keepTimestamps
+	FillUpdateFields    = fillUpdateFields
+	UpdateOf            = updateOf
+	PaginatePipeline    = paginatePipeline
+	PagesOf             = pagesOf
+	CursorSorts         = cursorSorts
+	KeysetFilter        = keysetFilter
+	EncodeCursor        = encodeCursor
+	DecodeCursor        = decodeCursor
+	CacheKeyOf          = cacheKeyOf
+	CacheRecord         = cacheRecord
+	ClearCache          = clearCache
+	UnitGroups          = unitGroups
+	UnitChunks          = unitChunks
+	BulkResultsOf       = bulkResultsOf
+	WatchTenantPipeline = watchTenantPipeline
+	TrashCondition      = trashCondition
+	RevisionFilter      = revisionFilter
+	EditableCondition   = editableCondition
+	DeletableCondition  = deletableCondition
+	ParseExplain        = parseExplain
+	ExplainCommand      = explainCommand
+	RawPipelineOf       = rawPipelineOf
+	TenantUnknown       = tenantUnknown
+	ContextOptionOf     = contextOptionOf
+	StampTenant         = stampTenant
+	TenantFilter        = tenantFilter
+	ParseError          = parseError
+	WriteErrorOf        = writeErrorOf
+)
+
+func CacheGenerationOf(collection string) uint64 {
+	return cacheGeneration(collection).Load()
+}
+
+func WithMiddlewares[R any](ctx context.Context, op *Operation, fn func(ctx context.Context) (R, error)) (R, error) {
+	return withMiddlewares(ctx, op, fn)
+}
+
+func ResetMiddlewares() {
+	middlewares = make([]Middleware, 0)
+}
+
+func (r *Repository[T]) OptionOf(opts ...MongoOption) MongoOption {
+	return r.optionOf(opts...)
+}
+
+func ReplaceTimestamps[T any](old *T, model Model, upsert bool) {
+	replaceTimestamps(old, model, upsert)
+}
+
+func DoneAfter(operation string, model Model, opt MongoOption, pipeline any, duration time.Duration) error {
+	l := logOf(operation, model, opt).pipe(pipeline)
+	l.start = time.Now().Add(-duration)
+	return l.done(context.TODO(), 1, nil)
+}
@@ -0,0 +1,207 @@
+package mongoutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LogEntry repository operation log
+type LogEntry struct {
+	Metric
+	// Pipeline executed pipeline or filter (only with DebugPipe option)
+	Pipeline any
+	// Result decoded records or write result (only with DebugResult option)
+	Result any
+	// Explain explain summary of read operation pipeline (only with Explain option)
+	Explain *ExplainSummary
+	// ExplainErr explain command error
+	ExplainErr error
+}
+
+// Logger repository operation logger
+type Logger interface {
+	// Log log operation
+	Log(ctx context.Context, entry LogEntry)
+}
+
+// globalLogger logger used by repository functions if no logger passed to option
+var globalLogger Logger
+
+// SetLogger set global repository logger
+// pass nil to disable global logger
+// repository operations logged to console if DebugPipe, DebugResult or Explain option passed and no logger set
+func SetLogger(logger Logger) {
+	globalLogger = logger
+}
+
+// loggerOf resolve operation logger (nil if logging disabled)
+func loggerOf(opt MongoOption) Logger {
+	if opt.Logger != nil {
+		return opt.Logger
+	}
+	if globalLogger != nil {
+		return globalLogger
+	}
+	if opt.DebugPipe || opt.DebugResult || opt.Explain {
+		return consoleLogger{}
+	}
+	return nil
+}
+
+// opLog operation log and metrics builder
+type opLog struct {
+	logger   Logger
+	opt      MongoOption
+	start    time.Time
+	entry    LogEntry
+	coll     *mongo.Collection
+	pipeline any
+}
+
+// logOf start operation log
+// call before database call to measure duration
+func logOf(operation string, model Model, opt MongoOption) *opLog {
+	coll := collectionOf(model, opt)
+	return &opLog{
+		logger: loggerOf(opt),
+		opt:    opt,
+		start:  time.Now(),
+		coll:   coll,
+		entry: LogEntry{
+			Metric: Metric{
+				Operation:  operation,
+				TypeName:   model.TypeName(),
+				Collection: coll.Name(),
+			},
+		},
+	}
+}
+
+// pipe set executed pipeline or filter
+// pipeline logged if DebugPipe option passed and reported on slow query
+func (l *opLog) pipe(pipeline any) *opLog {
+	l.pipeline = pipeline
+	if l.opt.DebugPipe {
+		l.entry.Pipeline = pipeline
+	}
+	return l
+}
+
+// result set operation result if DebugResult option passed
+func (l *opLog) result(result any) *opLog {
+	if l.opt.DebugResult {
+		l.entry.Result = result
+	}
+	return l
+}
+
+// done log operation, report metrics and slow query and return err
+func (l *opLog) done(ctx context.Context, count int64, err error) error {
+	l.entry.Duration = time.Since(l.start)
+	l.entry.Count = count
+	l.entry.Err = err
+	if l.opt.Explain && err == nil {
+		l.explain()
+	}
+	if l.logger != nil {
+		l.logger.Log(ctx, l.entry)
+	}
+	if globalMetrics != nil {
+		globalMetrics.Observe(ctx, l.entry.Metric)
+	}
+	if slowQuery.handler != nil && slowQuery.threshold > 0 && l.entry.Duration >= slowQuery.threshold {
+		l.reportSlow(ctx)
+	}
+	return err
+}
+
+// explain explain operation pipeline and set log entry explain summary
+// explain run without operation context session, because explain not allowed inside transaction
+func (l *opLog) explain() {
+	if pipe, ok := l.pipeline.(mongo.Pipeline); ok {
+		ctx, cancel := MongoOperationCtx()
+		defer cancel()
+		l.entry.Explain, l.entry.ExplainErr = explainPipeline(ctx, l.coll, pipe, "executionStats", l.opt)
+	}
+}
+
+// consoleLogger print operation to stdout
+type consoleLogger struct{}
+
+func (consoleLogger) Log(ctx context.Context, entry LogEntry) {
+	fmt.Printf("============= %s =============\n", entry.Operation)
+	fmt.Printf("TYPE: %s\nCOLLECTION: %s\nDURATION: %s\nCOUNT: %d\n", entry.TypeName, entry.Collection, entry.Duration, entry.Count)
+	if entry.Err != nil {
+		fmt.Println("ERROR: " + entry.Err.Error())
+	}
+	if entry.Pipeline != nil {
+		fmt.Println("PIPELINE:")
+		prettyLog(entry.Pipeline)
+	}
+	if entry.Result != nil {
+		fmt.Println("RESULT:")
+		prettyLog(entry.Result)
+	}
+	if entry.ExplainErr != nil {
+		fmt.Println("EXPLAIN ERROR: " + entry.ExplainErr.Error())
+	}
+	if entry.Explain != nil {
+		fmt.Printf("PLAN: %s\nINDEXES: %v\nEXAMINED: %d docs, %d keys\nRETURNED: %d\n", entry.Explain.WinningPlan, entry.Explain.Indexes, entry.Explain.DocsExamined, entry.Explain.KeysExamined, entry.Explain.DocsReturned)
+		for _, warning := range entry.Explain.Warnings() {
+			fmt.Println("WARNING: " + warning)
+		}
+	}
+	fmt.Println("==========================================")
+}
+
+// slogLogger log operation using slog logger
+// failed operations logged on error level, operations with explain warnings on warn level and others on debug level
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (sl slogLogger) Log(ctx context.Context, entry LogEntry) {
+	level := slog.LevelDebug
+	attrs := []slog.Attr{
+		slog.String("operation", entry.Operation),
+		slog.String("type", entry.TypeName),
+		slog.String("collection", entry.Collection),
+		slog.Duration("duration", entry.Duration),
+		slog.Int64("count", entry.Count),
+	}
+	if entry.Pipeline != nil {
+		attrs = append(attrs, slog.Any("pipeline", entry.Pipeline))
+	}
+	if entry.Result != nil {
+		attrs = append(attrs, slog.Any("result", entry.Result))
+	}
+	if entry.ExplainErr != nil {
+		attrs = append(attrs, slog.String("explain_error", entry.ExplainErr.Error()))
+	}
+	if entry.Explain != nil {
+		warnings := entry.Explain.Warnings()
+		if len(warnings) > 0 {
+			level = slog.LevelWarn
+		}
+		attrs = append(attrs, slog.Group("explain",
+			slog.String("plan", entry.Explain.WinningPlan),
+			slog.Any("indexes", entry.Explain.Indexes),
+			slog.Int64("docs_examined", entry.Explain.DocsExamined),
+			slog.Int64("keys_examined", entry.Explain.KeysExamined),
+			slog.Int64("docs_returned", entry.Explain.DocsReturned),
+			slog.Any("warnings", warnings),
+		))
+	}
+	if entry.Err != nil {
+		if !errors.Is(entry.Err, ErrNotFound) {
+			level = slog.LevelError
+		}
+		attrs = append(attrs, slog.String("error", entry.Err.Error()))
+	}
+	sl.logger.LogAttrs(ctx, level, "mongoutils "+entry.Operation, attrs...)
+}
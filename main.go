@@ -1,7 +1,9 @@
 package mongoutils
 
 import (
+	"container/list"
 	"context"
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -34,9 +36,79 @@ func NewMetaSetter() MetaSetter {
 	return res
 }
 
-// MongoOperationCtx create context for mongo db operations for 10 sec
+// NewUnitOfWork new unit of work for bulk model changes
+func NewUnitOfWork(opts ...MongoOption) UnitOfWork {
+	res := new(unitOfWork)
+	res.opts = opts
+	res.entries = make([]unitEntry, 0)
+	return res
+}
+
+// NewRepository new model repository with default option and timeout
+// default option merged with operation option, operation option fields has priority
+// boolean flags ORed, flag enabled on default option can not disabled per operation
+// use separate repository or package level functions to run operation without default flag
+// timeout ignored on 0
+func NewRepository[T any](option MongoOption, timeout time.Duration) *Repository[T] {
+	res := new(Repository[T])
+	res.option = option
+	res.timeout = timeout
+	return res
+}
+
+// NewMemoryCache new in memory LRU read cache with TTL
+// least recently used records removed when cache size exceeded
+// size and ttl ignored on 0
+func NewMemoryCache(size int, ttl time.Duration) Cache {
+	res := new(memoryCache)
+	res.size = size
+	res.ttl = ttl
+	res.order = list.New()
+	res.entries = make(map[string]*list.Element)
+	res.collections = make(map[string]map[string]*list.Element)
+	return res
+}
+
+// NewSlogLogger new repository logger using slog logger
+// slog default logger used if nil passed
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return slogLogger{logger: logger}
+}
+
+// NewConsoleLogger new repository logger that print operations to stdout
+func NewConsoleLogger() Logger {
+	return consoleLogger{}
+}
+
+// DefaultRetryPolicy retry policy with 3 attempts and 100ms exponential backoff up to 2s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     100 * time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+	}
+}
+
+// operationTimeout timeout of package level non context functions
+var operationTimeout = 10 * time.Second
+
+// SetOperationTimeout set timeout of MongoOperationCtx used by package level non context functions
+// pass 0 to disable timeout
+func SetOperationTimeout(timeout time.Duration) {
+	if timeout >= 0 {
+		operationTimeout = timeout
+	}
+}
+
+// MongoOperationCtx create context for mongo db operations with operation timeout (10 sec by default)
 func MongoOperationCtx() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.TODO(), 10*time.Second)
+	if operationTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), operationTimeout)
 }
 
 // ParseObjectID parse object id from string
@@ -0,0 +1,87 @@
+package mongoutils
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Metric repository operation timing metric
+type Metric struct {
+	// Operation repository operation name (e.g. find, update)
+	Operation string
+	// TypeName model type name
+	TypeName string
+	// Collection model collection name
+	Collection string
+	// Duration database call duration
+	Duration time.Duration
+	// Count returned records count for read and affected records count for write operations
+	Count int64
+	// Err operation error
+	Err error
+}
+
+// MetricsHook receive metric of every repository operation
+type MetricsHook interface {
+	// Observe record operation metric
+	// called synchronously after operation, avoid blocking work
+	Observe(ctx context.Context, metric Metric)
+}
+
+// SlowQuery operation took longer than slow query threshold
+type SlowQuery struct {
+	Metric
+	// Pipeline executed pipeline or filter
+	Pipeline any
+	// Explain explain summary of aggregate pipeline (nil for write operations or if explain disabled)
+	Explain *ExplainSummary
+	// ExplainErr explain command error
+	ExplainErr error
+}
+
+// SlowQueryHandler receive slow queries
+type SlowQueryHandler func(ctx context.Context, query SlowQuery)
+
+// globalMetrics metrics hook of repository operations
+var globalMetrics MetricsHook
+
+// slowQuery slow query detection config
+var slowQuery struct {
+	threshold time.Duration
+	explain   bool
+	handler   SlowQueryHandler
+}
+
+// SetMetricsHook set global repository metrics hook
+// pass nil to disable metrics
+func SetMetricsHook(hook MetricsHook) {
+	globalMetrics = hook
+}
+
+// SetSlowQuery report repository operations slower than threshold to handler
+// on explain mode aggregate pipeline of slow read operations explained after operation with queryPlanner verbosity
+// query planner explain not execute pipeline again but add extra database call to caller path
+// execution stats reported only if operation run with Explain option
+// pass zero threshold or nil handler to disable slow query detection
+func SetSlowQuery(threshold time.Duration, explain bool, handler SlowQueryHandler) {
+	slowQuery.threshold = threshold
+	slowQuery.explain = explain
+	slowQuery.handler = handler
+}
+
+// reportSlow explain operation pipeline and pass to slow query handler
+// explain run with queryPlanner verbosity to not execute slow pipeline again
+// explain run without operation context session, because explain not allowed inside transaction
+func (l *opLog) reportSlow(ctx context.Context) {
+	query := SlowQuery{Metric: l.entry.Metric, Pipeline: l.pipeline}
+	if l.entry.Explain != nil || l.entry.ExplainErr != nil {
+		query.Explain, query.ExplainErr = l.entry.Explain, l.entry.ExplainErr
+	} else if pipe, ok := l.pipeline.(mongo.Pipeline); ok && slowQuery.explain {
+		explainCtx, cancel := MongoOperationCtx()
+		defer cancel()
+		query.Explain, query.ExplainErr = explainPipeline(explainCtx, l.coll, pipe, "queryPlanner", l.opt)
+	}
+	slowQuery.handler(ctx, query)
+}
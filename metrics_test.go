@@ -0,0 +1,61 @@
+package mongoutils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSlowQuery(t *testing.T) {
+	// client connect lazily, no database call on non pipeline operations
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI("mongodb://127.0.0.1:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.TODO())
+	opt := mongoutils.MongoOption{Database: client.Database("test")}
+
+	reported := make([]mongoutils.SlowQuery, 0)
+	mongoutils.SetSlowQuery(100*time.Millisecond, true, func(ctx context.Context, q mongoutils.SlowQuery) {
+		reported = append(reported, q)
+	})
+	defer mongoutils.SetSlowQuery(0, false, nil)
+
+	filter := primitive.M{"name": "John"}
+	mongoutils.DoneAfter("update", new(watchItem), opt, filter, 10*time.Millisecond)
+	if len(reported) != 0 {
+		t.Fatal("fast operation reported")
+	}
+
+	mongoutils.DoneAfter("update", new(watchItem), opt, filter, 200*time.Millisecond)
+	if len(reported) != 1 {
+		t.Fatal("slow operation not reported")
+	}
+	q := reported[0]
+	if q.Operation != "update" || q.TypeName != "watch_item" || q.Collection != "watch_items" || q.Count != 1 {
+		t.Fatalf("invalid metric %+v", q.Metric)
+	}
+	if q.Duration < 100*time.Millisecond {
+		t.Fatalf("invalid duration %s", q.Duration)
+	}
+	if v, ok := q.Pipeline.(primitive.M); !ok || v["name"] != "John" {
+		t.Fatalf("invalid pipeline %v", q.Pipeline)
+	}
+	if q.Explain != nil || q.ExplainErr != nil {
+		t.Fatal("write operation must not explained")
+	}
+
+	// disabled
+	mongoutils.SetSlowQuery(0, false, func(ctx context.Context, q mongoutils.SlowQuery) {
+		reported = append(reported, q)
+	})
+	mongoutils.DoneAfter("update", new(watchItem), opt, filter, 200*time.Millisecond)
+	if len(reported) != 1 {
+		t.Fatal("disabled slow query reported")
+	}
+}
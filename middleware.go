@@ -0,0 +1,94 @@
+package mongoutils
+
+import "context"
+
+// Middleware operation names
+const (
+	OpFind              = "find"
+	OpFindRaw           = "find_raw"
+	OpFindOne           = "find_one"
+	OpFindEach          = "find_each"
+	OpFindRawEach       = "find_raw_each"
+	OpFindSeq           = "find_seq"
+	OpFindRawSeq        = "find_raw_seq"
+	OpFindPaginated     = "find_paginated"
+	OpFindCursor        = "find_cursor"
+	OpCount             = "count"
+	OpCountRaw          = "count_raw"
+	OpExplain           = "explain"
+	OpInsert            = "insert"
+	OpInsertMany        = "insert_many"
+	OpUpdate            = "update"
+	OpUpsert            = "upsert"
+	OpDelete            = "delete"
+	OpSoftDelete        = "soft_delete"
+	OpRestore           = "restore"
+	OpPatch             = "patch"
+	OpBatchUpdate       = "batch_update"
+	OpIncrement         = "increment"
+	OpFindOneAndUpdate  = "find_one_and_update"
+	OpFindOneAndReplace = "find_one_and_replace"
+	OpFindOneAndDelete  = "find_one_and_delete"
+)
+
+// Operation repository operation passed to middlewares
+// middlewares can inspect and mutate operation before calling next
+type Operation struct {
+	// Name operation name (OpFind, OpInsert, ...)
+	Name string
+	// Model operation model (model zero value for find, count, insert many and batch operations)
+	// model fields can mutated for insert, update, upsert, delete, soft delete and restore operations
+	Model Model
+	// Models inserted models of insert many operation
+	Models []Model
+	// Filter find filter, batch operation condition or find one and modify filter (nil for single model and raw operations)
+	// filter of raw operations matched before raw pipeline stages
+	Filter any
+	// Pipeline model pipeline of read operations (filter, sorts, skip and limit added after middlewares) or raw operation pipeline
+	Pipeline MongoPipeline
+	// Update patch data (primitive.M), batch update document, increment data or find one and update document
+	Update any
+	// Option operation option (read only)
+	Option MongoOption
+	// Result operation result (set after next called)
+	Result any
+}
+
+// Middleware wrap repository operation
+// call next to continue operation or return error without calling next to veto operation
+type Middleware func(ctx context.Context, op *Operation, next func(ctx context.Context) error) error
+
+// middlewares global middlewares in registration order
+var middlewares = make([]Middleware, 0)
+
+// UseMiddleware register global repository middlewares
+// middlewares run in registration order (first registered is outermost) before model hooks
+// for HookTx option middlewares run inside transaction
+func UseMiddleware(mws ...Middleware) {
+	middlewares = append(middlewares, mws...)
+}
+
+// withMiddlewares run fn wrapped by global middlewares
+func withMiddlewares[R any](ctx context.Context, op *Operation, fn func(ctx context.Context) (R, error)) (R, error) {
+	var res R
+	next := func(ctx context.Context) error {
+		var err error
+		res, err = fn(ctx)
+		op.Result = res
+		return err
+	}
+	if !op.Option.IgnoreMiddlewares {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			mw, inner := middlewares[i], next
+			next = func(ctx context.Context) error {
+				return mw(ctx, op, inner)
+			}
+		}
+	}
+	return res, next(ctx)
+}
+
+// rawPipelineOf prepend operation filter to raw operation pipeline (filter ignored on nil)
+func rawPipelineOf(op *Operation) MongoPipeline {
+	return appendStages(NewPipe().Match(op.Filter), op.Pipeline)
+}
@@ -0,0 +1,109 @@
+package mongoutils_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	defer mongoutils.ResetMiddlewares()
+	calls := make([]string, 0)
+	track := func(name string) mongoutils.Middleware {
+		return func(ctx context.Context, op *mongoutils.Operation, next func(ctx context.Context) error) error {
+			calls = append(calls, name+" before")
+			err := next(ctx)
+			calls = append(calls, name+" after")
+			return err
+		}
+	}
+	mongoutils.UseMiddleware(track("first"), track("second"))
+	mongoutils.UseMiddleware(track("third"))
+
+	op := &mongoutils.Operation{Name: mongoutils.OpFind}
+	res, err := mongoutils.WithMiddlewares(context.Background(), op, func(ctx context.Context) (int, error) {
+		calls = append(calls, "operation")
+		return 3, nil
+	})
+	if err != nil || res != 3 || op.Result != 3 {
+		t.Fatalf("unexpected result %v, %v, %v", res, op.Result, err)
+	}
+	expected := []string{
+		"first before", "second before", "third before",
+		"operation",
+		"third after", "second after", "first after",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+}
+
+func TestMiddlewareFilter(t *testing.T) {
+	defer mongoutils.ResetMiddlewares()
+	mongoutils.UseMiddleware(func(ctx context.Context, op *mongoutils.Operation, next func(ctx context.Context) error) error {
+		op.Filter = primitive.M{"$and": primitive.A{op.Filter, primitive.M{"hidden": false}}}
+		return next(ctx)
+	})
+
+	op := &mongoutils.Operation{Name: mongoutils.OpFindOne, Filter: primitive.M{"name": "John"}}
+	filter, err := mongoutils.WithMiddlewares(context.Background(), op, func(ctx context.Context) (any, error) {
+		return op.Filter, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"$and":[{"name":"John"},{"hidden":false}]}`
+	if got, _ := pretty(filter); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestMiddlewareVeto(t *testing.T) {
+	defer mongoutils.ResetMiddlewares()
+	denied := errors.New("permission denied")
+	mongoutils.UseMiddleware(func(ctx context.Context, op *mongoutils.Operation, next func(ctx context.Context) error) error {
+		if op.Name == mongoutils.OpDelete {
+			return denied
+		}
+		return next(ctx)
+	})
+
+	called := false
+	operation := func(ctx context.Context) (bool, error) {
+		called = true
+		return true, nil
+	}
+	op := &mongoutils.Operation{Name: mongoutils.OpDelete}
+	if _, err := mongoutils.WithMiddlewares(context.Background(), op, operation); !errors.Is(err, denied) {
+		t.Errorf("expected veto error, got %v", err)
+	}
+	if called || op.Result != nil {
+		t.Error("vetoed operation called")
+	}
+
+	op = &mongoutils.Operation{Name: mongoutils.OpDelete, Option: mongoutils.MongoOption{IgnoreMiddlewares: true}}
+	if _, err := mongoutils.WithMiddlewares(context.Background(), op, operation); err != nil || !called {
+		t.Errorf("expected middlewares ignored, got %v", err)
+	}
+}
+
+func TestRawPipelineOf(t *testing.T) {
+	raw := mongoutils.NewPipe().Group(func(d mongoutils.MongoDoc) mongoutils.MongoDoc {
+		return d.Add("_id", "$city")
+	})
+	op := &mongoutils.Operation{Name: mongoutils.OpFindRaw, Pipeline: raw}
+	if got, _ := pretty(mongoutils.RawPipelineOf(op).Build()); got != `[[{"Key":"$group","Value":[{"Key":"_id","Value":"$city"}]}]]` {
+		t.Errorf("unexpected pipeline without filter %s", got)
+	}
+
+	// middleware filter must match before raw stages
+	op.Filter = primitive.M{"tenant": "acme"}
+	expected := `[[{"Key":"$match","Value":{"tenant":"acme"}}],[{"Key":"$group","Value":[{"Key":"_id","Value":"$city"}]}]]`
+	if got, _ := pretty(mongoutils.RawPipelineOf(op).Build()); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
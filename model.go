@@ -2,6 +2,7 @@ package mongoutils
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -33,7 +34,7 @@ type Model interface {
 	// by default returns true on BaseModel
 	IsEditable() bool
 	// IsDeletable check if document is deletable
-	// by default returns false on BaseModel
+	// by default returns true on BaseModel
 	IsDeletable() bool
 	// Cleanup document before save
 	// e.g set document field nil for ignore saving
@@ -52,6 +53,20 @@ type Model interface {
 	OnDeleted(ctx context.Context, opt ...MongoOption) error
 }
 
+// BatchEditable model with filter-level editable condition
+// repository batch update functions only update records matched by EditableFilter
+type BatchEditable interface {
+	// EditableFilter get filter of editable records (ignored on nil)
+	EditableFilter() any
+}
+
+// BatchDeletable model with filter-level deletable condition
+// repository filter based delete functions only delete records matched by DeletableFilter
+type BatchDeletable interface {
+	// DeletableFilter get filter of deletable records (ignored on nil)
+	DeletableFilter() any
+}
+
 type SchemaVersioning interface {
 	// GetVersion get schema version
 	GetVersion() int
@@ -59,6 +74,26 @@ type SchemaVersioning interface {
 	SetVersion(int)
 }
 
+type RevisionVersioning interface {
+	// RevisionField get revision field name (e.g. revision)
+	RevisionField() string
+	// GetRevision get document revision
+	GetRevision() int64
+	// SetRevision set document revision
+	SetRevision(int64)
+}
+
+type Timestamps interface {
+	// GetCreatedAt get created_at field
+	GetCreatedAt() time.Time
+	// SetCreatedAt set created_at field
+	SetCreatedAt(time.Time)
+	// GetUpdatedAt get updated_at field
+	GetUpdatedAt() *time.Time
+	// SetUpdatedAt set updated_at field
+	SetUpdatedAt(*time.Time)
+}
+
 type SoftDelete interface {
 	// SoftDelete set deleted_at field to current date
 	SoftDelete()
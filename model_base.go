@@ -44,6 +44,22 @@ func (model *BaseModel) FillUpdatedAt() {
 	model.UpdatedAt = &now
 }
 
+func (model BaseModel) GetCreatedAt() time.Time {
+	return model.CreatedAt
+}
+
+func (model *BaseModel) SetCreatedAt(t time.Time) {
+	model.CreatedAt = t
+}
+
+func (model BaseModel) GetUpdatedAt() *time.Time {
+	return model.UpdatedAt
+}
+
+func (model *BaseModel) SetUpdatedAt(t *time.Time) {
+	model.UpdatedAt = t
+}
+
 func (model *BaseModel) NewId() {
 	model.ID = primitive.NewObjectID()
 }
@@ -61,7 +77,7 @@ func (BaseModel) IsEditable() bool {
 }
 
 func (BaseModel) IsDeletable() bool {
-	return false
+	return true
 }
 
 func (*BaseModel) Cleanup() {}
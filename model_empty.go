@@ -53,7 +53,7 @@ func (EmptyModel) IsEditable() bool {
 }
 
 func (EmptyModel) IsDeletable() bool {
-	return false
+	return true
 }
 
 func (*EmptyModel) Cleanup() {}
@@ -0,0 +1,18 @@
+package mongoutils
+
+// RevisionModel document revision field for optimistic concurrency control
+type RevisionModel struct {
+	Revision int64 `bson:"revision" json:"revision"`
+}
+
+func (RevisionModel) RevisionField() string {
+	return "revision"
+}
+
+func (model RevisionModel) GetRevision() int64 {
+	return model.Revision
+}
+
+func (model *RevisionModel) SetRevision(v int64) {
+	model.Revision = v
+}
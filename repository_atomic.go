@@ -0,0 +1,393 @@
+package mongoutils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindOneAndOption find one and modify operation option
+type FindOneAndOption struct {
+	// Sort select first record of sorted matches (ignored on nil)
+	Sort any
+	// Projection returned document fields (ignored on nil)
+	Projection any
+	// Upsert insert new record if no record matched (update and replace only)
+	Upsert bool
+	// ReturnNew return document after modification instead of before (update and replace only)
+	ReturnNew bool
+	// Silent disable update meta (updated_at) (update and replace only)
+	Silent bool
+}
+
+// FindOneAndUpdate atomically update first record matched by filter and return it
+// updated_at field set on non silent mode like Patch
+// soft deleted records not matched unless WithTrashed or OnlyTrashed option set
+// this function not call model hooks
+// nil returned without error if record inserted on upsert mode and ReturnNew not set
+//
+// @param ctx operation context
+// @param filter record filter
+// @param updates update document or update pipeline
+// @param fo find one and update option
+// @opts operation option
+func FindOneAndUpdateCtx[T any](
+	ctx context.Context,
+	filter any,
+	updates any,
+	fo FindOneAndOption,
+	opts ...MongoOption,
+) (*T, error) {
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return modifyTx(ctx, model, opt, func(ctx context.Context) (*T, error) {
+		op := &Operation{Name: OpFindOneAndUpdate, Model: model, Filter: filter, Update: updates, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*T, error) {
+			filter, err := editableCondition(model, op.Filter, opt)
+			if err != nil {
+				return nil, err
+			}
+			if filter, err = tenantCondition(model, filter, opt); err != nil {
+				return nil, err
+			}
+			filter = trashCondition(model, filter, opt)
+			update := op.Update
+			if !fo.Silent {
+				update = touchUpdate(op.Update, time.Now().UTC())
+			}
+			option := options.FindOneAndUpdate().
+				SetUpsert(fo.Upsert).
+				SetReturnDocument(returnDocumentOf(fo))
+			if fo.Sort != nil {
+				option.SetSort(fo.Sort)
+			}
+			if fo.Projection != nil {
+				option.SetProjection(fo.Projection)
+			}
+			modifyOptionOf(option, opt)
+			_, audited := parseAsInterface[Auditable](model)
+			var before primitive.M
+			if audited {
+				if before, err = matchedDoc(ctx, model, filter, fo.Sort, opt); err != nil {
+					return nil, err
+				}
+				filter = pinCondition(filter, before)
+			}
+			log := logOf("find_one_and_update", model, opt).pipe(primitive.M{"filter": filter, "update": update})
+			res, err := retryOf(ctx, opt, false, func() (*T, error) {
+				return decodeSingle[T](collectionOf(model, opt).FindOneAndUpdate(ctx, filterOf(filter), update, option), fo)
+			})
+			invalidateCache(ctx, model, opt)
+			if err == nil && audited {
+				err = auditModify(ctx, "find_one_and_update", model, before, filter, fo, false, opt)
+				if err != nil {
+					err = HookError{Hook: "Audit", Result: res, Err: err}
+				}
+			}
+			return res, log.result(res).done(ctx, countOf(res), err)
+		})
+	})
+}
+func FindOneAndUpdate[T any](filter any, updates any, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return FindOneAndUpdateCtx[T](ctx, filter, updates, fo, opts...)
+}
+
+// FindOneAndReplace atomically replace first record matched by filter with model and return it
+// model cleaned up and updated_at filled on non silent mode
+// created_at and updated_at of matched record kept, created_at filled only if record inserted on upsert mode
+// soft deleted records not matched unless WithTrashed or OnlyTrashed option set
+// this function not call model hooks
+// nil returned without error if record inserted on upsert mode and ReturnNew not set
+//
+// @param ctx operation context
+// @param filter record filter
+// @param v replacement model (id ignored if zero)
+// @param fo find one and replace option
+// @opts operation option
+func FindOneAndReplaceCtx[T any](
+	ctx context.Context,
+	filter any,
+	v *T,
+	fo FindOneAndOption,
+	opts ...MongoOption,
+) (*T, error) {
+	model := modelSafe(v)
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return modifyTx(ctx, model, opt, func(ctx context.Context) (*T, error) {
+		op := &Operation{Name: OpFindOneAndReplace, Model: model, Filter: filter, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*T, error) {
+			filter, err := editableCondition(typeModelSafe[T](), op.Filter, opt)
+			if err != nil {
+				return nil, err
+			}
+			if filter, err = tenantCondition(model, filter, opt); err != nil {
+				return nil, err
+			}
+			filter = trashCondition(model, filter, opt)
+			before, err := matchedDoc(ctx, model, filter, fo.Sort, opt)
+			if err != nil {
+				return nil, err
+			}
+			// restrict replace to loaded record to keep its timestamps
+			filter = pinCondition(filter, before)
+			var old *T
+			if before != nil {
+				old = new(T)
+				if err := decodeDoc(before, old); err != nil {
+					return nil, err
+				}
+			}
+			replaceTimestamps(old, model, fo.Upsert)
+			model.Cleanup()
+			if !fo.Silent {
+				model.FillUpdatedAt()
+			}
+			FillBackupFields(v)
+			if err := stampTenant(model, opt); err != nil {
+				return nil, err
+			}
+			option := options.FindOneAndReplace().
+				SetUpsert(fo.Upsert).
+				SetReturnDocument(returnDocumentOf(fo))
+			if fo.Sort != nil {
+				option.SetSort(fo.Sort)
+			}
+			if fo.Projection != nil {
+				option.SetProjection(fo.Projection)
+			}
+			modifyOptionOf(option, opt)
+			log := logOf("find_one_and_replace", model, opt).pipe(primitive.M{"filter": filter, "replacement": v})
+			res, err := retryOf(ctx, opt, true, func() (*T, error) {
+				return decodeSingle[T](collectionOf(model, opt).FindOneAndReplace(ctx, filterOf(filter), v, option), fo)
+			})
+			invalidateCache(ctx, model, opt)
+			if _, audited := parseAsInterface[Auditable](model); err == nil && audited {
+				err = auditModify(ctx, "find_one_and_replace", model, before, filter, fo, false, opt)
+				if err != nil {
+					err = HookError{Hook: "Audit", Result: res, Err: err}
+				}
+			}
+			return res, log.result(res).done(ctx, countOf(res), err)
+		})
+	})
+}
+func FindOneAndReplace[T any](filter any, v *T, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return FindOneAndReplaceCtx(ctx, filter, v, fo, opts...)
+}
+
+// FindOneAndDelete atomically delete first record matched by filter and return deleted record
+// soft deleted records not matched unless WithTrashed or OnlyTrashed option set
+// this function not call model hooks
+// Upsert, ReturnNew and Silent option ignored
+//
+// @param ctx operation context
+// @param filter record filter
+// @param fo find one and delete option
+// @opts operation option
+func FindOneAndDeleteCtx[T any](
+	ctx context.Context,
+	filter any,
+	fo FindOneAndOption,
+	opts ...MongoOption,
+) (*T, error) {
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return modifyTx(ctx, model, opt, func(ctx context.Context) (*T, error) {
+		op := &Operation{Name: OpFindOneAndDelete, Model: model, Filter: filter, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*T, error) {
+			filter, err := deletableCondition(model, op.Filter, opt)
+			if err != nil {
+				return nil, err
+			}
+			if filter, err = tenantCondition(model, filter, opt); err != nil {
+				return nil, err
+			}
+			filter = trashCondition(model, filter, opt)
+			option := options.FindOneAndDelete()
+			if fo.Sort != nil {
+				option.SetSort(fo.Sort)
+			}
+			if fo.Projection != nil {
+				option.SetProjection(fo.Projection)
+			}
+			modifyOptionOf(option, opt)
+			_, audited := parseAsInterface[Auditable](model)
+			var before primitive.M
+			if audited {
+				if before, err = matchedDoc(ctx, model, filter, fo.Sort, opt); err != nil {
+					return nil, err
+				}
+				filter = pinCondition(filter, before)
+			}
+			log := logOf("find_one_and_delete", model, opt).pipe(filter)
+			res, err := retryOf(ctx, opt, false, func() (*T, error) {
+				return decodeSingle[T](collectionOf(model, opt).FindOneAndDelete(ctx, filterOf(filter), option), FindOneAndOption{})
+			})
+			invalidateCache(ctx, model, opt)
+			if err == nil && audited {
+				err = auditModify(ctx, "find_one_and_delete", model, before, filter, FindOneAndOption{}, true, opt)
+				if err != nil {
+					err = HookError{Hook: "Audit", Result: res, Err: err}
+				}
+			}
+			return res, log.result(res).done(ctx, countOf(res), err)
+		})
+	})
+}
+func FindOneAndDelete[T any](filter any, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return FindOneAndDeleteCtx[T](ctx, filter, fo, opts...)
+}
+
+// decodeSingle decode find one and modify result
+func decodeSingle[T any](sr *mongo.SingleResult, fo FindOneAndOption) (*T, error) {
+	res := new(T)
+	if err := sr.Decode(res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if fo.Upsert && !fo.ReturnNew {
+				return nil, nil
+			}
+			return nil, ErrNotFound
+		}
+		return nil, parseError(err)
+	}
+	return res, nil
+}
+
+// modifyOption driver find one and modify options
+type modifyOption[O any] interface {
+	SetCollation(collation *options.Collation) O
+	SetHint(hint any) O
+	SetMaxTime(d time.Duration) O
+	SetComment(comment any) O
+}
+
+// modifyOptionOf apply option collation, hint, max time and comment to find one and modify options
+func modifyOptionOf[O modifyOption[O]](option O, opt MongoOption) {
+	if opt.Collation != nil {
+		option.SetCollation(opt.Collation)
+	}
+	if opt.Hint != nil {
+		option.SetHint(opt.Hint)
+	}
+	if opt.MaxTime > 0 {
+		option.SetMaxTime(opt.MaxTime)
+	}
+	if opt.Comment != "" {
+		option.SetComment(opt.Comment)
+	}
+}
+
+// modifyTx run find one and modify operation inside transaction on HookTx option
+// operations of auditable models always run inside transaction to load audit snapshots consistently with write
+func modifyTx[R any](ctx context.Context, model Model, opt MongoOption, fn func(ctx context.Context) (R, error)) (R, error) {
+	if _, audited := parseAsInterface[Auditable](model); audited {
+		opt.HookTx, opt.IgnoreHooks = true, false
+	}
+	return hookTx(ctx, opt, fn)
+}
+
+// matchedDoc load full document of first record matched by find one and modify filter (nil if not matched)
+func matchedDoc(ctx context.Context, model Model, filter any, sorts any, opt MongoOption) (primitive.M, error) {
+	option := options.FindOne()
+	if sorts != nil {
+		option.SetSort(sorts)
+	}
+	if opt.Collation != nil {
+		option.SetCollation(opt.Collation)
+	}
+	res := primitive.M{}
+	if err := collectionOf(model, opt).FindOne(ctx, filterOf(filter), option).Decode(&res); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, parseError(err)
+	}
+	return res, nil
+}
+
+// pinCondition restrict condition to loaded document (ignored on nil document)
+func pinCondition(condition any, doc primitive.M) any {
+	if doc == nil {
+		return condition
+	}
+	return andCondition(condition, primitive.M{"_id": doc["_id"]})
+}
+
+// replaceTimestamps keep created_at and updated_at of matched record on replacement model
+// created_at filled only if no record matched on upsert mode (record inserted)
+func replaceTimestamps[T any](old *T, model Model, upsert bool) {
+	if old != nil {
+		keepTimestamps(old, model)
+	} else if upsert {
+		model.FillCreatedAt()
+	}
+}
+
+// countOf get returned document count of find one and modify result
+func countOf[T any](v *T) int64 {
+	if v == nil {
+		return 0
+	}
+	return 1
+}
+
+// returnDocumentOf get driver return document option
+func returnDocumentOf(fo FindOneAndOption) options.ReturnDocument {
+	if fo.ReturnNew {
+		return options.After
+	}
+	return options.Before
+}
+
+// filterOf get non nil filter for driver operations
+func filterOf(filter any) any {
+	if filter == nil {
+		return primitive.M{}
+	}
+	return filter
+}
+
+// touchUpdate add updated_at to $set of update document or as last stage of update pipeline
+func touchUpdate(updates any, now time.Time) any {
+	touch := primitive.M{"$set": primitive.M{"updated_at": now}}
+	if _, isDoc := updates.(primitive.D); !isDoc {
+		if val := reflect.ValueOf(updates); val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+			res := make(primitive.A, 0, val.Len()+1)
+			for i := 0; i < val.Len(); i++ {
+				res = append(res, val.Index(i).Interface())
+			}
+			return append(res, touch)
+		}
+	}
+	doc, err := toDocMap(updates)
+	if err != nil {
+		return updates
+	}
+	set, ok := asDocMap(doc["$set"])
+	if !ok {
+		set = primitive.M{}
+	}
+	set["updated_at"] = now
+	doc["$set"] = set
+	return doc
+}
@@ -0,0 +1,319 @@
+package mongoutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// cursorSecret secret key used to sign cursor tokens
+var cursorSecret = randomSecret()
+
+// SetCursorSecret set secret key used to sign cursor tokens
+// by default random key generated on startup and tokens are invalid after restart
+func SetCursorSecret(secret []byte) {
+	if len(secret) > 0 {
+		cursorSecret = secret
+	}
+}
+
+// CursorResult keyset paginated find result
+type CursorResult[T any] struct {
+	Items   []T    `json:"items"`
+	Next    string `json:"next"`
+	Prev    string `json:"prev"`
+	HasNext bool   `json:"has_next"`
+	HasPrev bool   `json:"has_prev"`
+}
+
+type cursorToken struct {
+	Prev   bool        `bson:"p"`
+	Sign   string      `bson:"s"`
+	Values primitive.A `bson:"v"`
+}
+
+// FindCursor find records using keyset pagination
+// _id added to sorts as tiebreaker if not exists
+// pass empty token for first page and result Next or Prev token for next and previous pages
+//
+// @param ctx operation context
+// @param filter (ignored on nil)
+// @param sorts ordered sort keys
+// @param token continuation token (ignored on empty)
+// @param limit page records count (must be greater than 0)
+// @opts operation option
+func FindCursorCtx[T any](
+	ctx context.Context,
+	filter any,
+	sorts primitive.D,
+	token string,
+	limit int64,
+	opts ...MongoOption,
+) (*CursorResult[T], error) {
+	res := &CursorResult[T]{Items: make([]T, 0)}
+	if limit <= 0 {
+		return res, errors.New("cursor limit must be greater than 0")
+	}
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return res, err
+	}
+	pipeline, err := modelPipeline(model, opt)
+	if err != nil {
+		return res, err
+	}
+
+	// resolve sorts and token
+	sorts = cursorSorts(sorts)
+	filterSign, err := cursorFilterSign(filter)
+	if err != nil {
+		return res, err
+	}
+	sign := model.TypeName() + "|" + opt.Pipeline + "|" + cursorSign(sorts) + "|" + filterSign
+	var cursor *cursorToken
+	if token != "" {
+		if cursor, err = decodeCursor(token, sign, len(sorts)); err != nil {
+			return res, err
+		}
+	}
+	isPrev := cursor != nil && cursor.Prev
+	querySorts := sorts
+	if isPrev {
+		querySorts = reverseSorts(sorts)
+	}
+	var keyset any
+	if cursor != nil {
+		keyset = keysetFilter(querySorts, cursor.Values)
+	}
+
+	op := &Operation{Name: OpFindCursor, Model: model, Filter: filter, Pipeline: pipeline, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (*CursorResult[T], error) {
+		pipe := op.Pipeline.
+			Match(op.Filter).
+			Match(keyset).
+			Sort(querySorts).
+			Limit(limit + 1).
+			Build()
+
+		log := logOf("find_cursor", model, opt).pipe(pipe)
+		raws := make([]bson.Raw, 0)
+		if cur, err := aggregate(ctx, collectionOf(model, opt), pipe, opt); err != nil {
+			return res, log.done(ctx, 0, err)
+		} else {
+			defer cur.Close(ctx)
+			for cur.Next(ctx) {
+				raws = append(raws, bytes.Clone(cur.Current))
+			}
+			if err := cur.Err(); err != nil {
+				return res, log.done(ctx, 0, err)
+			}
+		}
+
+		// trim extra record and restore order
+		hasMore := int64(len(raws)) > limit
+		if hasMore {
+			raws = raws[:limit]
+		}
+		if isPrev {
+			for i, j := 0, len(raws)-1; i < j; i, j = i+1, j-1 {
+				raws[i], raws[j] = raws[j], raws[i]
+			}
+		}
+		for _, raw := range raws {
+			v := new(T)
+			if err := bson.Unmarshal(raw, v); err != nil {
+				return res, log.done(ctx, 0, err)
+			}
+			res.Items = append(res.Items, *v)
+		}
+		log.result(res.Items).done(ctx, int64(len(res.Items)), nil)
+
+		// generate tokens
+		res.HasNext = hasMore || isPrev
+		res.HasPrev = cursor != nil && (!isPrev || hasMore)
+		if len(raws) > 0 {
+			if res.HasNext {
+				if res.Next, err = encodeCursor(false, sign, sorts, raws[len(raws)-1]); err != nil {
+					return res, err
+				}
+			}
+			if res.HasPrev {
+				if res.Prev, err = encodeCursor(true, sign, sorts, raws[0]); err != nil {
+					return res, err
+				}
+			}
+		}
+		return res, nil
+	})
+}
+func FindCursor[T any](filter any, sorts primitive.D, token string, limit int64, opts ...MongoOption) (*CursorResult[T], error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return FindCursorCtx[T](ctx, filter, sorts, token, limit, opts...)
+}
+
+// cursorSorts normalize sort directions and add _id as tiebreaker
+func cursorSorts(sorts primitive.D) primitive.D {
+	res := make(primitive.D, 0, len(sorts)+1)
+	hasID := false
+	dir := 1
+	for _, s := range sorts {
+		dir = sortDirection(s.Value)
+		hasID = hasID || s.Key == "_id"
+		res = append(res, primitive.E{Key: s.Key, Value: dir})
+	}
+	if !hasID {
+		res = append(res, primitive.E{Key: "_id", Value: dir})
+	}
+	return res
+}
+
+// sortDirection get 1 for ascending and -1 for descending sort value
+func sortDirection(v any) int {
+	switch d := v.(type) {
+	case int:
+		return dirOf(d < 0)
+	case int32:
+		return dirOf(d < 0)
+	case int64:
+		return dirOf(d < 0)
+	case float32:
+		return dirOf(d < 0)
+	case float64:
+		return dirOf(d < 0)
+	case string:
+		return dirOf(strings.EqualFold(d, "desc") || d == "-1")
+	}
+	return 1
+}
+
+func dirOf(desc bool) int {
+	if desc {
+		return -1
+	}
+	return 1
+}
+
+// reverseSorts invert sort directions
+func reverseSorts(sorts primitive.D) primitive.D {
+	res := make(primitive.D, len(sorts))
+	for i, s := range sorts {
+		res[i] = primitive.E{Key: s.Key, Value: -s.Value.(int)}
+	}
+	return res
+}
+
+// cursorSign generate sorts signature
+func cursorSign(sorts primitive.D) string {
+	keys := make([]string, len(sorts))
+	for i, s := range sorts {
+		keys[i] = fmt.Sprintf("%s:%d", s.Key, s.Value)
+	}
+	return strings.Join(keys, ",")
+}
+
+// cursorFilterSign generate filter (or pipeline) signature
+// json encoding used because map keys sorted on json encoding
+func cursorFilterSign(filter any) (string, error) {
+	if filter == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// keysetFilter generate filter for records after values in sorts order
+// null and missing values sorted before other values
+//
+// {$or: [{k1: {$gt: v1}}, {k1: v1, k2: {$gt: v2}}, ...]}
+func keysetFilter(sorts primitive.D, values primitive.A) primitive.M {
+	conditions := make(primitive.A, 0, len(sorts))
+	for i, s := range sorts {
+		condition := primitive.M{}
+		for j := 0; j < i; j++ {
+			condition[sorts[j].Key] = values[j]
+		}
+		asc := s.Value.(int) > 0
+		switch {
+		case values[i] == nil && asc:
+			// all non null values are after null
+			condition[s.Key] = primitive.M{"$ne": nil}
+		case values[i] == nil:
+			// nothing is before null
+			continue
+		case asc:
+			condition[s.Key] = primitive.M{"$gt": values[i]}
+		default:
+			// null values are after all values in descending order
+			condition["$or"] = primitive.A{
+				primitive.M{s.Key: primitive.M{"$lt": values[i]}},
+				primitive.M{s.Key: nil},
+			}
+		}
+		conditions = append(conditions, condition)
+	}
+	return primitive.M{"$or": conditions}
+}
+
+// encodeCursor generate signed token from record sort keys value
+func encodeCursor(prev bool, sign string, sorts primitive.D, raw bson.Raw) (string, error) {
+	token := cursorToken{Prev: prev, Sign: sign, Values: make(primitive.A, len(sorts))}
+	for i, s := range sorts {
+		if v, err := raw.LookupErr(strings.Split(s.Key, ".")...); err == nil {
+			token.Values[i] = v
+		}
+	}
+	payload, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(append(payload, cursorMAC(payload)...)), nil
+}
+
+// decodeCursor validate and parse token
+func decodeCursor(token string, sign string, keys int) (*cursorToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) <= sha256.Size {
+		return nil, ErrInvalidCursor
+	}
+	payload, mac := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if !hmac.Equal(mac, cursorMAC(payload)) {
+		return nil, ErrInvalidCursor
+	}
+	res := new(cursorToken)
+	if err := bson.Unmarshal(payload, res); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if res.Sign != sign || len(res.Values) != keys {
+		return nil, ErrInvalidCursor
+	}
+	return res, nil
+}
+
+func cursorMAC(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
@@ -0,0 +1,114 @@
+package mongoutils_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorToken(t *testing.T) {
+	id := primitive.NewObjectID()
+	raw, err := bson.Marshal(primitive.M{"_id": id, "name": "John", "updated_at": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorts := mongoutils.CursorSorts(primitive.D{{Key: "name", Value: 1}, {Key: "updated_at", Value: "desc"}})
+	if v, _ := pretty(sorts); v != `[{"Key":"name","Value":1},{"Key":"updated_at","Value":-1},{"Key":"_id","Value":-1}]` {
+		t.Fatal("fail sorts: " + v)
+	}
+
+	// encode and decode
+	token, err := mongoutils.EncodeCursor(true, "sign", sorts, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := mongoutils.DecodeCursor(token, "sign", len(sorts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cursor.Prev || len(cursor.Values) != 3 ||
+		cursor.Values[0] != "John" || cursor.Values[1] != nil || cursor.Values[2] != id {
+		t.Fatalf("fail decode: %v", cursor)
+	}
+
+	// other query
+	if _, err := mongoutils.DecodeCursor(token, "other", len(sorts)); !errors.Is(err, mongoutils.ErrInvalidCursor) {
+		t.Fatal("fail sign check")
+	}
+	if _, err := mongoutils.DecodeCursor(token, "sign", 2); !errors.Is(err, mongoutils.ErrInvalidCursor) {
+		t.Fatal("fail keys check")
+	}
+
+	// tampered
+	data, _ := base64.RawURLEncoding.DecodeString(token)
+	data[len(data)/2] ^= 1
+	if _, err := mongoutils.DecodeCursor(base64.RawURLEncoding.EncodeToString(data), "sign", len(sorts)); !errors.Is(err, mongoutils.ErrInvalidCursor) {
+		t.Fatal("fail tamper check")
+	}
+	if _, err := mongoutils.DecodeCursor("invalid!", "sign", len(sorts)); !errors.Is(err, mongoutils.ErrInvalidCursor) {
+		t.Fatal("fail malformed check")
+	}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	var v string
+	var err error
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// ascending
+	v, err = pretty(mongoutils.KeysetFilter(
+		primitive.D{{Key: "name", Value: 1}, {Key: "age", Value: 1}},
+		primitive.A{"John", 10},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `{"$or":[{"name":{"$gt":"John"}},{"age":{"$gt":10},"name":"John"}]}` {
+		t.Log(v)
+		t.Fatal("fail ascending")
+	}
+
+	// descending include null values
+	v, err = pretty(mongoutils.KeysetFilter(
+		primitive.D{{Key: "updated_at", Value: -1}, {Key: "age", Value: -1}},
+		primitive.A{at, 10},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `{"$or":[{"$or":[{"updated_at":{"$lt":"2020-01-01T00:00:00Z"}},{"updated_at":null}]},{"$or":[{"age":{"$lt":10}},{"age":null}],"updated_at":"2020-01-01T00:00:00Z"}]}` {
+		t.Log(v)
+		t.Fatal("fail descending")
+	}
+
+	// null ascending
+	v, err = pretty(mongoutils.KeysetFilter(
+		primitive.D{{Key: "updated_at", Value: 1}, {Key: "age", Value: 1}},
+		primitive.A{nil, 10},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `{"$or":[{"updated_at":{"$ne":null}},{"age":{"$gt":10},"updated_at":null}]}` {
+		t.Log(v)
+		t.Fatal("fail null ascending")
+	}
+
+	// null descending
+	v, err = pretty(mongoutils.KeysetFilter(
+		primitive.D{{Key: "updated_at", Value: -1}, {Key: "age", Value: -1}},
+		primitive.A{nil, 10},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `{"$or":[{"$or":[{"age":{"$lt":10}},{"age":null}],"updated_at":null}]}` {
+		t.Log(v)
+		t.Fatal("fail null descending")
+	}
+}
@@ -0,0 +1,364 @@
+package mongoutils
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Repository model repository bound to default option and timeout
+// methods are same as package level repository functions
+// non context methods use background context and context methods use passed context as parent
+type Repository[T any] struct {
+	option  MongoOption
+	timeout time.Duration
+}
+
+// Option get repository default option
+func (r *Repository[T]) Option() MongoOption {
+	return r.option
+}
+
+// Database get repository database
+func (r *Repository[T]) Database() *mongo.Database {
+	return r.option.Database
+}
+
+// Collection get model collection
+func (r *Repository[T]) Collection() *mongo.Collection {
+	return typeModelSafe[T]().Collection(r.option.Database)
+}
+
+// optionOf merge operation option with repository default option
+// empty fields of operation option filled from default option and flags enabled if enabled on default option
+func (r *Repository[T]) optionOf(opts ...MongoOption) MongoOption {
+	if len(opts) == 0 {
+		return r.option
+	}
+	opt, def := opts[0], r.option
+	if opt.Database == nil {
+		opt.Database = def.Database
+	}
+	if opt.Pipeline == "" {
+		opt.Pipeline = def.Pipeline
+		if len(opt.Params) == 0 {
+			opt.Params = def.Params
+		}
+	}
+	if opt.Logger == nil {
+		opt.Logger = def.Logger
+	}
+	if opt.Retry == nil {
+		opt.Retry = def.Retry
+	}
+	if opt.ReadPreference == nil {
+		opt.ReadPreference = def.ReadPreference
+	}
+	if opt.ReadConcern == nil {
+		opt.ReadConcern = def.ReadConcern
+	}
+	if opt.WriteConcern == nil {
+		opt.WriteConcern = def.WriteConcern
+	}
+	if opt.Collation == nil {
+		opt.Collation = def.Collation
+	}
+	if opt.Hint == nil {
+		opt.Hint = def.Hint
+	}
+	if opt.MaxTime == 0 {
+		opt.MaxTime = def.MaxTime
+	}
+	if opt.Comment == "" {
+		opt.Comment = def.Comment
+	}
+	if opt.Tenant == "" {
+		opt.Tenant = def.Tenant
+	}
+	if opt.Cache == nil {
+		opt.Cache = def.Cache
+	}
+	opt.IgnoreHooks = opt.IgnoreHooks || def.IgnoreHooks
+	opt.DebugPipe = opt.DebugPipe || def.DebugPipe
+	opt.DebugResult = opt.DebugResult || def.DebugResult
+	opt.WithTrashed = opt.WithTrashed || def.WithTrashed
+	opt.OnlyTrashed = opt.OnlyTrashed || def.OnlyTrashed
+	opt.HookTx = opt.HookTx || def.HookTx
+	opt.IgnoreGuards = opt.IgnoreGuards || def.IgnoreGuards
+	opt.IgnoreMiddlewares = opt.IgnoreMiddlewares || def.IgnoreMiddlewares
+	opt.Explain = opt.Explain || def.Explain
+	opt.IgnoreTenant = opt.IgnoreTenant || def.IgnoreTenant
+	opt.NoCache = opt.NoCache || def.NoCache
+	return opt
+}
+
+// context create operation context with repository timeout
+func (r *Repository[T]) context(parent context.Context) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if r.timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, r.timeout)
+}
+
+func (r *Repository[T]) FindCtx(ctx context.Context, filter any, sorts any, skip int64, limit int64, opts ...MongoOption) ([]T, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindCtx[T](ctx, filter, sorts, skip, limit, r.optionOf(opts...))
+}
+func (r *Repository[T]) Find(filter any, sorts any, skip int64, limit int64, opts ...MongoOption) ([]T, error) {
+	return r.FindCtx(context.Background(), filter, sorts, skip, limit, opts...)
+}
+
+func (r *Repository[T]) FindRawCtx(ctx context.Context, pipeline MongoPipeline, opts ...MongoOption) ([]T, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindRawCtx[T](ctx, pipeline, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindRaw(pipeline MongoPipeline, opts ...MongoOption) ([]T, error) {
+	return r.FindRawCtx(context.Background(), pipeline, opts...)
+}
+
+func (r *Repository[T]) FindOneCtx(ctx context.Context, filter any, sorts any, opts ...MongoOption) (*T, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindOneCtx[T](ctx, filter, sorts, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindOne(filter any, sorts any, opts ...MongoOption) (*T, error) {
+	return r.FindOneCtx(context.Background(), filter, sorts, opts...)
+}
+
+func (r *Repository[T]) FindEachCtx(ctx context.Context, filter any, sorts any, skip int64, limit int64, cb func(v *T) error, opts ...MongoOption) error {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindEachCtx(ctx, filter, sorts, skip, limit, cb, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindEach(filter any, sorts any, skip int64, limit int64, cb func(v *T) error, opts ...MongoOption) error {
+	return r.FindEachCtx(context.Background(), filter, sorts, skip, limit, cb, opts...)
+}
+
+func (r *Repository[T]) FindRawEachCtx(ctx context.Context, pipeline MongoPipeline, cb func(v *T) error, opts ...MongoOption) error {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindRawEachCtx(ctx, pipeline, cb, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindRawEach(pipeline MongoPipeline, cb func(v *T) error, opts ...MongoOption) error {
+	return r.FindRawEachCtx(context.Background(), pipeline, cb, opts...)
+}
+
+func (r *Repository[T]) FindSeqCtx(ctx context.Context, filter any, sorts any, skip int64, limit int64, opts ...MongoOption) Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		ctx, cancel := r.context(ctx)
+		defer cancel()
+		FindSeqCtx[T](ctx, filter, sorts, skip, limit, r.optionOf(opts...))(yield)
+	}
+}
+
+func (r *Repository[T]) FindRawSeqCtx(ctx context.Context, pipeline MongoPipeline, opts ...MongoOption) Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		ctx, cancel := r.context(ctx)
+		defer cancel()
+		FindRawSeqCtx[T](ctx, pipeline, r.optionOf(opts...))(yield)
+	}
+}
+
+func (r *Repository[T]) FindPaginatedCtx(ctx context.Context, filter any, sorts any, page int64, perPage int64, opts ...MongoOption) (*PaginateResult[T], error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindPaginatedCtx[T](ctx, filter, sorts, page, perPage, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindPaginated(filter any, sorts any, page int64, perPage int64, opts ...MongoOption) (*PaginateResult[T], error) {
+	return r.FindPaginatedCtx(context.Background(), filter, sorts, page, perPage, opts...)
+}
+
+func (r *Repository[T]) FindCursorCtx(ctx context.Context, filter any, sorts primitive.D, token string, limit int64, opts ...MongoOption) (*CursorResult[T], error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindCursorCtx[T](ctx, filter, sorts, token, limit, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindCursor(filter any, sorts primitive.D, token string, limit int64, opts ...MongoOption) (*CursorResult[T], error) {
+	return r.FindCursorCtx(context.Background(), filter, sorts, token, limit, opts...)
+}
+
+func (r *Repository[T]) ExplainCtx(ctx context.Context, filter any, sorts any, skip int64, limit int64, opts ...MongoOption) (*ExplainSummary, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return ExplainCtx[T](ctx, filter, sorts, skip, limit, r.optionOf(opts...))
+}
+func (r *Repository[T]) Explain(filter any, sorts any, skip int64, limit int64, opts ...MongoOption) (*ExplainSummary, error) {
+	return r.ExplainCtx(context.Background(), filter, sorts, skip, limit, opts...)
+}
+
+func (r *Repository[T]) InsertCtx(ctx context.Context, v *T, opts ...MongoOption) (*mongo.InsertOneResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return InsertCtx(ctx, v, r.optionOf(opts...))
+}
+func (r *Repository[T]) Insert(v *T, opts ...MongoOption) (*mongo.InsertOneResult, error) {
+	return r.InsertCtx(context.Background(), v, opts...)
+}
+
+func (r *Repository[T]) InsertManyCtx(ctx context.Context, items []*T, ordered bool, opts ...MongoOption) (*InsertManyResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return InsertManyCtx(ctx, items, ordered, r.optionOf(opts...))
+}
+func (r *Repository[T]) InsertMany(items []*T, ordered bool, opts ...MongoOption) (*InsertManyResult, error) {
+	return r.InsertManyCtx(context.Background(), items, ordered, opts...)
+}
+
+func (r *Repository[T]) UpdateCtx(ctx context.Context, v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return UpdateCtx(ctx, v, silent, r.optionOf(opts...))
+}
+func (r *Repository[T]) Update(v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	return r.UpdateCtx(context.Background(), v, silent, opts...)
+}
+
+func (r *Repository[T]) UpsertCtx(ctx context.Context, filter any, v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return UpsertCtx(ctx, filter, v, silent, r.optionOf(opts...))
+}
+func (r *Repository[T]) Upsert(filter any, v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	return r.UpsertCtx(context.Background(), filter, v, silent, opts...)
+}
+
+func (r *Repository[T]) DeleteCtx(ctx context.Context, v *T, opts ...MongoOption) (*mongo.DeleteResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return DeleteCtx(ctx, v, r.optionOf(opts...))
+}
+func (r *Repository[T]) Delete(v *T, opts ...MongoOption) (*mongo.DeleteResult, error) {
+	return r.DeleteCtx(context.Background(), v, opts...)
+}
+
+func (r *Repository[T]) SoftDeleteCtx(ctx context.Context, v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return SoftDeleteCtx(ctx, v, silent, r.optionOf(opts...))
+}
+func (r *Repository[T]) SoftDelete(v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	return r.SoftDeleteCtx(context.Background(), v, silent, opts...)
+}
+
+func (r *Repository[T]) RestoreCtx(ctx context.Context, v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return RestoreCtx(ctx, v, silent, r.optionOf(opts...))
+}
+func (r *Repository[T]) Restore(v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	return r.RestoreCtx(context.Background(), v, silent, opts...)
+}
+
+func (r *Repository[T]) ForceDeleteCtx(ctx context.Context, v *T, opts ...MongoOption) (*mongo.DeleteResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return ForceDeleteCtx(ctx, v, r.optionOf(opts...))
+}
+func (r *Repository[T]) ForceDelete(v *T, opts ...MongoOption) (*mongo.DeleteResult, error) {
+	return r.ForceDeleteCtx(context.Background(), v, opts...)
+}
+
+func (r *Repository[T]) CountCtx(ctx context.Context, filter any, opts ...MongoOption) (int64, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return CountCtx[T](ctx, filter, r.optionOf(opts...))
+}
+func (r *Repository[T]) Count(filter any, opts ...MongoOption) (int64, error) {
+	return r.CountCtx(context.Background(), filter, opts...)
+}
+
+func (r *Repository[T]) CountRawCtx(ctx context.Context, pipeline MongoPipeline, opts ...MongoOption) (int64, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return CountRawCtx[T](ctx, pipeline, r.optionOf(opts...))
+}
+func (r *Repository[T]) CountRaw(pipeline MongoPipeline, opts ...MongoOption) (int64, error) {
+	return r.CountRawCtx(context.Background(), pipeline, opts...)
+}
+
+func (r *Repository[T]) BatchUpdateCtx(ctx context.Context, condition any, updates any, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return BatchUpdateCtx[T](ctx, condition, updates, r.optionOf(opts...))
+}
+func (r *Repository[T]) BatchUpdate(condition any, updates any, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	return r.BatchUpdateCtx(context.Background(), condition, updates, opts...)
+}
+
+func (r *Repository[T]) PatchCtx(ctx context.Context, condition any, data primitive.M, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return PatchCtx[T](ctx, condition, data, silent, r.optionOf(opts...))
+}
+func (r *Repository[T]) Patch(condition any, data primitive.M, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	return r.PatchCtx(context.Background(), condition, data, silent, opts...)
+}
+
+func (r *Repository[T]) IncrementCtx(ctx context.Context, condition any, data any, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return IncrementCtx[T](ctx, condition, data, r.optionOf(opts...))
+}
+func (r *Repository[T]) Increment(condition any, data any, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	return r.IncrementCtx(context.Background(), condition, data, opts...)
+}
+
+func (r *Repository[T]) FindOneAndUpdateCtx(ctx context.Context, filter any, updates any, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindOneAndUpdateCtx[T](ctx, filter, updates, fo, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindOneAndUpdate(filter any, updates any, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	return r.FindOneAndUpdateCtx(context.Background(), filter, updates, fo, opts...)
+}
+
+func (r *Repository[T]) FindOneAndReplaceCtx(ctx context.Context, filter any, v *T, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindOneAndReplaceCtx(ctx, filter, v, fo, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindOneAndReplace(filter any, v *T, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	return r.FindOneAndReplaceCtx(context.Background(), filter, v, fo, opts...)
+}
+
+func (r *Repository[T]) FindOneAndDeleteCtx(ctx context.Context, filter any, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return FindOneAndDeleteCtx[T](ctx, filter, fo, r.optionOf(opts...))
+}
+func (r *Repository[T]) FindOneAndDelete(filter any, fo FindOneAndOption, opts ...MongoOption) (*T, error) {
+	return r.FindOneAndDeleteCtx(context.Background(), filter, fo, opts...)
+}
+
+func (r *Repository[T]) AuditHistoryCtx(ctx context.Context, id primitive.ObjectID, skip int64, limit int64, opts ...MongoOption) ([]AuditRecord, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return AuditHistoryCtx[T](ctx, id, skip, limit, r.optionOf(opts...))
+}
+func (r *Repository[T]) AuditHistory(id primitive.ObjectID, skip int64, limit int64, opts ...MongoOption) ([]AuditRecord, error) {
+	return r.AuditHistoryCtx(context.Background(), id, skip, limit, opts...)
+}
+
+// WatchCtx watch model collection changes (repository timeout not applied)
+func (r *Repository[T]) WatchCtx(ctx context.Context, wo WatchOption, cb func(event ChangeEvent[T]) error, opts ...MongoOption) error {
+	return WatchCtx(ctx, wo, cb, r.optionOf(opts...))
+}
+
+func (r *Repository[T]) ResumeTokenOf(ctx context.Context, wo WatchOption, opts ...MongoOption) (bson.Raw, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+	return ResumeTokenOf[T](ctx, wo, r.optionOf(opts...))
+}
+
+// UnitOfWork new unit of work with repository option
+func (r *Repository[T]) UnitOfWork(opts ...MongoOption) UnitOfWork {
+	return NewUnitOfWork(r.optionOf(opts...))
+}
@@ -0,0 +1,84 @@
+package mongoutils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type repositoryUser struct {
+	mongoutils.BaseModel `bson:",inline"`
+	Name                 string `bson:"name"`
+}
+
+func TestRepositoryOptionOf(t *testing.T) {
+	db, other := new(mongo.Database), new(mongo.Database)
+	repo := mongoutils.NewRepository[repositoryUser](mongoutils.MongoOption{
+		Database:    db,
+		Pipeline:    "Lookup",
+		Params:      []any{"en"},
+		MaxTime:     time.Second,
+		Hint:        "name_1",
+		Comment:     "default",
+		Tenant:      "acme",
+		WithTrashed: true,
+	}, 0)
+
+	// no operation option
+	if opt := repo.OptionOf(); opt.Database != db || opt.Pipeline != "Lookup" || opt.Comment != "default" {
+		t.Errorf("default option not returned: %+v", opt)
+	}
+
+	// empty fields filled from default and flags enabled by any
+	opt := repo.OptionOf(mongoutils.MongoOption{Comment: "find", IgnoreHooks: true})
+	if opt.Database != db || opt.Pipeline != "Lookup" || len(opt.Params) != 1 || opt.MaxTime != time.Second || opt.Hint != "name_1" || opt.Tenant != "acme" {
+		t.Errorf("empty fields not filled: %+v", opt)
+	}
+	if opt.Comment != "find" {
+		t.Errorf("operation comment overridden: %s", opt.Comment)
+	}
+	if !opt.IgnoreHooks || !opt.WithTrashed {
+		t.Errorf("flags not merged: %+v", opt)
+	}
+
+	// default flags can not disabled per operation
+	if opt := repo.OptionOf(mongoutils.MongoOption{WithTrashed: false}); !opt.WithTrashed {
+		t.Errorf("default flag disabled: %+v", opt)
+	}
+
+	// operation fields has priority, default params ignored for operation pipeline
+	opt = repo.OptionOf(mongoutils.MongoOption{Database: other, Pipeline: "Pipeline", MaxTime: time.Minute, Hint: "_id_", Tenant: "globex"})
+	if opt.Database != other || opt.Pipeline != "Pipeline" || len(opt.Params) != 0 || opt.MaxTime != time.Minute || opt.Hint != "_id_" || opt.Tenant != "globex" {
+		t.Errorf("operation option not prioritized: %+v", opt)
+	}
+
+	// default params kept for default pipeline
+	opt = repo.OptionOf(mongoutils.MongoOption{Params: []any{"fa"}})
+	if opt.Pipeline != "Lookup" || len(opt.Params) != 1 || opt.Params[0] != "fa" {
+		t.Errorf("operation params overridden: %+v", opt)
+	}
+}
+
+func TestMongoOperationCtx(t *testing.T) {
+	defer mongoutils.SetOperationTimeout(10 * time.Second)
+
+	mongoutils.SetOperationTimeout(time.Minute)
+	ctx, cancel := mongoutils.MongoOperationCtx()
+	deadline, ok := ctx.Deadline()
+	cancel()
+	if !ok || time.Until(deadline) > time.Minute || time.Until(deadline) < 50*time.Second {
+		t.Errorf("unexpected deadline %v", deadline)
+	}
+
+	mongoutils.SetOperationTimeout(0)
+	ctx, cancel = mongoutils.MongoOperationCtx()
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("deadline set for disabled timeout")
+	}
+	if ctx.Err() != nil {
+		t.Error("context canceled")
+	}
+}
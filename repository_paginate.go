@@ -0,0 +1,131 @@
+package mongoutils
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PaginateResult paginated find result
+type PaginateResult[T any] struct {
+	Items   []T   `json:"items"`
+	Total   int64 `json:"total"`
+	Page    int64 `json:"page"`
+	PerPage int64 `json:"per_page"`
+	Pages   int64 `json:"pages"`
+}
+
+type facetResult[T any] struct {
+	Items []T           `bson:"items"`
+	Total []countResult `bson:"total"`
+}
+
+// FindPaginated find records of page with total records count in single query
+// this function use $facet stage to get items and count
+//
+// @param ctx operation context
+// @param filter (ignored on nil)
+// @param sorts (ignored on nil)
+// @param page page number (start from 1, values less than 1 treated as 1)
+// @param perPage page records count (return all records on 0 or negative value)
+// @opts operation option
+func FindPaginatedCtx[T any](
+	ctx context.Context,
+	filter any,
+	sorts any,
+	page int64,
+	perPage int64,
+	opts ...MongoOption,
+) (*PaginateResult[T], error) {
+	res := &PaginateResult[T]{Items: make([]T, 0), Page: page, PerPage: perPage}
+	if res.Page < 1 {
+		res.Page = 1
+	}
+	if res.PerPage < 0 {
+		res.PerPage = 0
+	}
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return res, err
+	}
+	pipeline, err := modelPipeline(model, opt)
+	if err != nil {
+		return res, err
+	}
+	op := &Operation{Name: OpFindPaginated, Model: model, Filter: filter, Pipeline: pipeline, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (*PaginateResult[T], error) {
+		pipe := paginatePipeline(op.Pipeline, op.Filter, sorts, res.Page, res.PerPage)
+
+		log := logOf("find_paginated", model, opt).pipe(pipe)
+		if cur, err := aggregate(ctx, collectionOf(model, opt), pipe, opt); err != nil {
+			return res, log.done(ctx, 0, err)
+		} else {
+			defer cur.Close(ctx)
+			for cur.Next(ctx) {
+				rec := new(facetResult[T])
+				if err := cur.Decode(rec); err != nil {
+					return res, log.done(ctx, 0, err)
+				}
+				if rec.Items != nil {
+					res.Items = rec.Items
+				}
+				if len(rec.Total) > 0 {
+					res.Total = rec.Total[0].Count
+				}
+			}
+			if err := cur.Err(); err != nil {
+				return res, log.done(ctx, 0, err)
+			}
+		}
+		res.Pages = pagesOf(res.Total, res.PerPage)
+		return res, log.result(res).done(ctx, int64(len(res.Items)), nil)
+	})
+}
+func FindPaginated[T any](filter any, sorts any, page int64, perPage int64, opts ...MongoOption) (*PaginateResult[T], error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return FindPaginatedCtx[T](ctx, filter, sorts, page, perPage, opts...)
+}
+
+// paginatePipeline generate $facet pipeline of page items and total count
+// empty items sub-pipeline replaced with no-op $skip stage, because $facet not accept empty pipeline
+func paginatePipeline(pipeline MongoPipeline, filter any, sorts any, page int64, perPage int64) mongo.Pipeline {
+	items := append(
+		mongo.Pipeline{},
+		NewPipe().
+			Sort(sorts).
+			Skip((page-1)*perPage).
+			Limit(perPage).
+			Build()...,
+	)
+	if len(items) == 0 {
+		items = append(items, primitive.D{{Key: "$skip", Value: 0}})
+	}
+	total := NewPipe().
+		Add(func(d MongoDoc) MongoDoc {
+			return d.Add("$count", "count")
+		})
+	return pipeline.
+		Match(filter).
+		Add(func(d MongoDoc) MongoDoc {
+			return d.Doc("$facet", func(d MongoDoc) MongoDoc {
+				return d.
+					Add("items", items).
+					Add("total", total.Build())
+			})
+		}).
+		Build()
+}
+
+// pagesOf calculate pages count
+func pagesOf(total int64, perPage int64) int64 {
+	if total <= 0 {
+		return 0
+	}
+	if perPage <= 0 {
+		return 1
+	}
+	return (total + perPage - 1) / perPage
+}
@@ -0,0 +1,57 @@
+package mongoutils_test
+
+import (
+	"testing"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPaginatePipeline(t *testing.T) {
+	var v string
+	var err error
+
+	// all records without sort
+	v, err = pretty(mongoutils.PaginatePipeline(mongoutils.NewPipe(), nil, nil, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `[[{"Key":"$facet","Value":[{"Key":"items","Value":[[{"Key":"$skip","Value":0}]]},{"Key":"total","Value":[[{"Key":"$count","Value":"count"}]]}]}]]` {
+		t.Log(v)
+		t.Fatal("fail empty items")
+	}
+
+	// page with filter and sort
+	v, err = pretty(mongoutils.PaginatePipeline(
+		mongoutils.NewPipe(),
+		primitive.M{"name": "John"},
+		primitive.M{"_id": 1},
+		3, 10,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `[[{"Key":"$match","Value":{"name":"John"}}],[{"Key":"$facet","Value":[{"Key":"items","Value":[[{"Key":"$sort","Value":{"_id":1}}],[{"Key":"$skip","Value":20}],[{"Key":"$limit","Value":10}]]},{"Key":"total","Value":[[{"Key":"$count","Value":"count"}]]}]}]]` {
+		t.Log(v)
+		t.Fatal("fail page")
+	}
+}
+
+func TestPagesOf(t *testing.T) {
+	cases := []struct {
+		total, perPage, pages int64
+	}{
+		{0, 10, 0},
+		{-1, 10, 0},
+		{5, 0, 1},
+		{5, -1, 1},
+		{10, 10, 1},
+		{11, 10, 2},
+		{25, 5, 5},
+	}
+	for _, c := range cases {
+		if v := mongoutils.PagesOf(c.total, c.perPage); v != c.pages {
+			t.Errorf("PagesOf(%d, %d) = %d, want %d", c.total, c.perPage, v, c.pages)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package mongoutils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SoftDelete set record deleted_at field to current date
+// this function call model delete hooks
+// model must implement SoftDelete
+// only context version available because SoftDelete is model interface name
+//
+// @param ctx operation context
+// @param v model
+// @param isSilent disable update meta (updated_at)
+// @opts operation option
+func SoftDeleteCtx[T any](
+	ctx context.Context,
+	v *T,
+	isSilent bool,
+	opts ...MongoOption,
+) (*mongo.UpdateResult, error) {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return hookTx(ctx, opt, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		model := modelSafe(v)
+		op := &Operation{Name: OpSoftDelete, Model: model, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.UpdateResult, error) {
+			trash, ok := parseAsInterface[SoftDelete](model)
+			if !ok {
+				return nil, errors.New(model.TypeName() + " model not implements SoftDelete")
+			}
+			if !opt.IgnoreGuards {
+				old, err := FindOneCtx[T](ctx, primitive.M{"_id": model.GetID()}, nil, withTrashed(opts...))
+				if err != nil {
+					return nil, err
+				}
+				if !modelSafe(old).IsDeletable() {
+					return nil, ErrNotDeletable
+				}
+			}
+			if err := stampTenant(model, opt); err != nil {
+				return nil, err
+			}
+			if !opt.IgnoreHooks {
+				if err := model.OnDelete(ctx, opts...); err != nil {
+					return nil, err
+				}
+			}
+			trash.SoftDelete()
+			res, err := setTrashState(ctx, "soft_delete", model, true, isSilent, opt)
+			if err != nil {
+				// record trashed if only audit failed
+				var hookErr HookError
+				if !errors.As(err, &hookErr) {
+					trash.Restore()
+				}
+				return res, err
+			}
+			if !opt.IgnoreHooks {
+				if err := model.OnDeleted(ctx, opts...); err != nil {
+					return res, HookError{Hook: "OnDeleted", Result: res, Err: err}
+				}
+			}
+			return res, nil
+		})
+	})
+}
+
+// Restore set record deleted_at field to nil
+// this function use FindOne to find old record and call model update hooks
+// model must implement SoftDelete
+//
+// @param ctx operation context
+// @param v model
+// @param isSilent disable update meta (updated_at)
+// @opts operation option
+func RestoreCtx[T any](
+	ctx context.Context,
+	v *T,
+	isSilent bool,
+	opts ...MongoOption,
+) (*mongo.UpdateResult, error) {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return hookTx(ctx, opt, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		model := modelSafe(v)
+		op := &Operation{Name: OpRestore, Model: model, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.UpdateResult, error) {
+			trash, ok := parseAsInterface[SoftDelete](model)
+			if !ok {
+				return nil, errors.New(model.TypeName() + " model not implements SoftDelete")
+			}
+			old, err := FindOneCtx[T](ctx, primitive.M{"_id": model.GetID()}, nil, withTrashed(opts...))
+			if err != nil {
+				return nil, err
+			}
+			if !opt.IgnoreGuards && !modelSafe(old).IsEditable() {
+				return nil, ErrNotEditable
+			}
+			if err := stampTenant(model, opt); err != nil {
+				return nil, err
+			}
+			if !opt.IgnoreHooks {
+				if err := model.OnUpdate(ctx, opts...); err != nil {
+					return nil, err
+				}
+			}
+			trash.Restore()
+			res, err := setTrashState(ctx, "restore", model, false, isSilent, opt)
+			if err != nil {
+				return res, err
+			}
+			if res.ModifiedCount > 0 && !opt.IgnoreHooks {
+				if err := model.OnUpdated(old, ctx, opts...); err != nil {
+					return res, HookError{Hook: "OnUpdated", Result: res, Err: err}
+				}
+			}
+			return res, nil
+		})
+	})
+}
+func Restore[T any](v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return RestoreCtx(ctx, v, silent, opts...)
+}
+
+// ForceDelete permanently delete record (soft deleted or not)
+// this function is same as Delete and used to clarify permanent delete of SoftDelete models
+//
+// @param ctx operation context
+// @param v model
+// @opts operation option
+func ForceDeleteCtx[T any](
+	ctx context.Context,
+	v *T,
+	opts ...MongoOption,
+) (*mongo.DeleteResult, error) {
+	return DeleteCtx(ctx, v, opts...)
+}
+func ForceDelete[T any](v *T, opts ...MongoOption) (*mongo.DeleteResult, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return ForceDeleteCtx(ctx, v, opts...)
+}
+
+// setTrashState update deleted_at, updated_at and revision of model record
+func setTrashState(ctx context.Context, operation string, model Model, deleted bool, silent bool, opt MongoOption) (*mongo.UpdateResult, error) {
+	now := time.Now().UTC()
+	data := primitive.M{"deleted_at": nil}
+	if deleted {
+		data["deleted_at"] = now
+	}
+	if !silent {
+		model.FillUpdatedAt()
+		data["updated_at"] = now
+	}
+	filter, rollback, versioned := revisionFilter(model, opt, true)
+	if versioned {
+		rev := model.(RevisionVersioning)
+		data[rev.RevisionField()] = rev.GetRevision()
+	}
+	update := Set(data)
+	log := logOf(operation, model, opt).pipe(primitive.M{"filter": filter, "update": update})
+	res, err := retryOf(ctx, opt, !versioned, func() (*mongo.UpdateResult, error) {
+		return collectionOf(model, opt).UpdateOne(ctx, filter, update, updateOptionOf(opt))
+	})
+	invalidateCache(ctx, model, opt)
+	if err != nil {
+		rollback()
+		return nil, log.done(ctx, 0, parseError(err))
+	}
+	log.result(res).done(ctx, res.ModifiedCount, nil)
+	if res.ModifiedCount > 0 {
+		if err := auditOf(ctx, model, operation, model.GetID(), ChangeSet{Set: data}, opt); err != nil {
+			return res, HookError{Hook: "Audit", Result: res, Err: err}
+		}
+	}
+	if versioned && res.MatchedCount == 0 {
+		rollback()
+		return res, conflictOf(model)
+	}
+	if res.MatchedCount == 0 {
+		return res, ErrNotFound
+	}
+	return res, nil
+}
@@ -0,0 +1,211 @@
+package mongoutils
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrStopIteration return from FindEach callback to stop iteration without error
+var ErrStopIteration = errors.New("stop iteration")
+
+// Seq2 iterator of key value pairs
+// same as go 1.23 iter.Seq2 and can used with range over func
+type Seq2[K, V any] func(yield func(K, V) bool)
+
+// FindEach iterate over find records one by one without buffering result
+// records not passed to logger on DebugResult mode
+// return ErrStopIteration from callback to stop iteration
+//
+// @param ctx operation context
+// @param filter (ignored on nil)
+// @param sorts (ignored on nil)
+// @param skip (ignored on 0)
+// @param limit (ignored on 0)
+// @param cb callback to call for each record
+// @opts operation option
+func FindEachCtx[T any](
+	ctx context.Context,
+	filter any,
+	sorts any,
+	skip int64,
+	limit int64,
+	cb func(v *T) error,
+	opts ...MongoOption,
+) error {
+	return findEachCtx(ctx, OpFindEach, filter, sorts, skip, limit, cb, opts...)
+}
+func FindEach[T any](filter any, sorts any, skip int64, limit int64, cb func(v *T) error, opts ...MongoOption) error {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return FindEachCtx(ctx, filter, sorts, skip, limit, cb, opts...)
+}
+
+// FindRawEach iterate over records of pipeline one by one without buffering result
+// option pipeline not effected
+// return ErrStopIteration from callback to stop iteration
+//
+// @param ctx operation context
+// @param pipeline aggregation pipeline
+// @param cb callback to call for each record
+// @opts operation option
+func FindRawEachCtx[T any](
+	ctx context.Context,
+	pipeline MongoPipeline,
+	cb func(v *T) error,
+	opts ...MongoOption,
+) error {
+	return findRawEachCtx(ctx, OpFindRawEach, pipeline, cb, opts...)
+}
+func FindRawEach[T any](pipeline MongoPipeline, cb func(v *T) error, opts ...MongoOption) error {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return FindRawEachCtx(ctx, pipeline, cb, opts...)
+}
+
+// FindSeq get find records iterator
+// iteration stops on first error
+//
+// @param ctx operation context
+// @param filter (ignored on nil)
+// @param sorts (ignored on nil)
+// @param skip (ignored on 0)
+// @param limit (ignored on 0)
+// @opts operation option
+func FindSeqCtx[T any](
+	ctx context.Context,
+	filter any,
+	sorts any,
+	skip int64,
+	limit int64,
+	opts ...MongoOption,
+) Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := findEachCtx(ctx, OpFindSeq, filter, sorts, skip, limit, func(v *T) error {
+			if !yield(v, nil) {
+				return ErrStopIteration
+			}
+			return nil
+		}, opts...)
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// FindRawSeq get pipeline records iterator
+// option pipeline not effected
+// iteration stops on first error
+//
+// @param ctx operation context
+// @param pipeline aggregation pipeline
+// @opts operation option
+func FindRawSeqCtx[T any](
+	ctx context.Context,
+	pipeline MongoPipeline,
+	opts ...MongoOption,
+) Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := findRawEachCtx(ctx, OpFindRawSeq, pipeline, func(v *T) error {
+			if !yield(v, nil) {
+				return ErrStopIteration
+			}
+			return nil
+		}, opts...)
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// findEachCtx iterate over find records wrapped by middlewares as name operation
+func findEachCtx[T any](
+	ctx context.Context,
+	name string,
+	filter any,
+	sorts any,
+	skip int64,
+	limit int64,
+	cb func(v *T) error,
+	opts ...MongoOption,
+) error {
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	pipeline, err := modelPipeline(model, opt)
+	if err != nil {
+		return err
+	}
+	op := &Operation{Name: name, Model: model, Filter: filter, Pipeline: pipeline, Option: opt}
+	_, err = withMiddlewares(ctx, op, func(ctx context.Context) (int64, error) {
+		pipe := op.Pipeline.
+			Match(op.Filter).
+			Sort(sorts).
+			Skip(skip).
+			Limit(limit).
+			Build()
+
+		log := logOf(name, model, opt).pipe(pipe)
+		if cur, err := aggregate(ctx, collectionOf(model, opt), pipe, opt); err != nil {
+			return 0, log.done(ctx, 0, err)
+		} else {
+			count, err := iterateCursor(ctx, cur, cb)
+			return count, log.done(ctx, count, err)
+		}
+	})
+	return err
+}
+
+// findRawEachCtx iterate over raw pipeline records wrapped by middlewares as name operation
+func findRawEachCtx[T any](
+	ctx context.Context,
+	name string,
+	pipeline MongoPipeline,
+	cb func(v *T) error,
+	opts ...MongoOption,
+) error {
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	if pipeline, err = tenantPipeline(model, pipeline, opt); err != nil {
+		return err
+	}
+	op := &Operation{Name: name, Model: model, Pipeline: pipeline, Option: opt}
+	_, err = withMiddlewares(ctx, op, func(ctx context.Context) (int64, error) {
+		pipe := rawPipelineOf(op).Build()
+
+		log := logOf(name, model, opt).pipe(pipe)
+		if cur, err := aggregate(ctx, collectionOf(model, opt), pipe, opt); err != nil {
+			return 0, log.done(ctx, 0, err)
+		} else {
+			count, err := iterateCursor(ctx, cur, cb)
+			return count, log.done(ctx, count, err)
+		}
+	})
+	return err
+}
+
+// iterateCursor decode cursor records one by one and pass to callback
+// cursor closed after iteration and iterated records count returned
+func iterateCursor[T any](ctx context.Context, cur *mongo.Cursor, cb func(v *T) error) (int64, error) {
+	defer cur.Close(ctx)
+	var count int64
+	for cur.Next(ctx) {
+		v := new(T)
+		if err := cur.Decode(v); err != nil {
+			return count, err
+		}
+		count++
+		if err := cb(v); errors.Is(err, ErrStopIteration) {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+	}
+	return count, cur.Err()
+}
@@ -1,18 +1,36 @@
 package mongoutils
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 type countResult struct {
 	Count int64 `bson:"count" json:"count"`
 }
 
+// InsertManyResult insert many operation result
+type InsertManyResult struct {
+	// InsertedIDs inserted records id by index
+	InsertedIDs map[int]primitive.ObjectID
+	// Failures failed records error by index
+	Failures map[int]error
+	// Skipped records index not inserted on ordered mode after first failure
+	Skipped []int
+}
+
 type MongoOption struct {
 	IgnoreHooks bool
 	DebugPipe   bool
@@ -20,6 +38,45 @@ type MongoOption struct {
 	Pipeline    string
 	Params      []any
 	Database    *mongo.Database
+	// WithTrashed include soft deleted records of SoftDelete models
+	WithTrashed bool
+	// OnlyTrashed only select soft deleted records of SoftDelete models
+	OnlyTrashed bool
+	// HookTx run write operation and hooks inside transaction
+	// write rolled back on post hook error
+	HookTx bool
+	// IgnoreGuards skip IsEditable, IsDeletable and batch filter checks for administrative tasks
+	IgnoreGuards bool
+	// Logger operation logger (global logger used on nil)
+	Logger Logger
+	// IgnoreMiddlewares skip global middlewares
+	IgnoreMiddlewares bool
+	// Retry operation retry policy (global retry policy used on nil)
+	Retry *RetryPolicy
+	// ReadPreference operation read preference (collection read preference used on nil)
+	ReadPreference *readpref.ReadPref
+	// ReadConcern operation read concern (collection read concern used on nil)
+	ReadConcern *readconcern.ReadConcern
+	// WriteConcern operation write concern (collection write concern used on nil)
+	WriteConcern *writeconcern.WriteConcern
+	// Collation operation collation (not supported by insert)
+	Collation *options.Collation
+	// Hint operation index hint, index name or index keys document (not supported by insert)
+	Hint any
+	// MaxTime server side time limit of aggregate and find and modify operations (ignored on 0)
+	MaxTime time.Duration
+	// Comment operation comment for profiler and logs (ignored on empty)
+	Comment string
+	// Explain explain pipeline of read operations after execution and log summary (extra database call)
+	Explain bool
+	// Tenant operation tenant of MultiTenant models (context tenant used on empty)
+	Tenant string
+	// IgnoreTenant skip tenant filter and tenant stamp of MultiTenant models for administrative tasks
+	IgnoreTenant bool
+	// Cache read cache of FindOne by id (global cache used on nil)
+	Cache Cache
+	// NoCache skip cache read of FindOne (writes still invalidate cache)
+	NoCache bool
 }
 
 // optionOf get option of dynamic params or return empty option
@@ -34,6 +91,94 @@ func optionOf(opts ...MongoOption) MongoOption {
 	return opt
 }
 
+// collectionOf get model collection with option read preference, read concern and write concern
+func collectionOf(model Model, opt MongoOption) *mongo.Collection {
+	coll := model.Collection(opt.Database)
+	if opt.ReadPreference == nil && opt.ReadConcern == nil && opt.WriteConcern == nil {
+		return coll
+	}
+	option := options.Collection()
+	if opt.ReadPreference != nil {
+		option.SetReadPreference(opt.ReadPreference)
+	}
+	if opt.ReadConcern != nil {
+		option.SetReadConcern(opt.ReadConcern)
+	}
+	if opt.WriteConcern != nil {
+		option.SetWriteConcern(opt.WriteConcern)
+	}
+	if res, err := coll.Clone(option); err == nil {
+		return res
+	}
+	return coll
+}
+
+// aggregateOptionOf generate aggregation options with option collation, hint, max time and comment
+func aggregateOptionOf(opt MongoOption) *options.AggregateOptions {
+	res := AggregateOption()
+	if opt.Collation != nil {
+		res.SetCollation(opt.Collation)
+	}
+	if opt.Hint != nil {
+		res.SetHint(opt.Hint)
+	}
+	if opt.MaxTime > 0 {
+		res.SetMaxTime(opt.MaxTime)
+	}
+	if opt.Comment != "" {
+		res.SetComment(opt.Comment)
+	}
+	return res
+}
+
+// insertOneOptionOf generate insert one options with option comment
+func insertOneOptionOf(opt MongoOption) *options.InsertOneOptions {
+	res := options.InsertOne()
+	if opt.Comment != "" {
+		res.SetComment(opt.Comment)
+	}
+	return res
+}
+
+// insertManyOptionOf generate insert many options with option comment
+func insertManyOptionOf(opt MongoOption, ordered bool) *options.InsertManyOptions {
+	res := options.InsertMany().SetOrdered(ordered)
+	if opt.Comment != "" {
+		res.SetComment(opt.Comment)
+	}
+	return res
+}
+
+// updateOptionOf generate update options with option collation, hint and comment
+func updateOptionOf(opt MongoOption) *options.UpdateOptions {
+	res := options.Update()
+	if opt.Collation != nil {
+		res.SetCollation(opt.Collation)
+	}
+	if opt.Hint != nil {
+		res.SetHint(opt.Hint)
+	}
+	if opt.Comment != "" {
+		res.SetComment(opt.Comment)
+	}
+	return res
+}
+
+// deleteOptionOf generate delete options with option collation, hint and comment
+func deleteOptionOf(opt MongoOption) *options.DeleteOptions {
+	res := options.Delete()
+	if opt.Collation != nil {
+		res.SetCollation(opt.Collation)
+	}
+	if opt.Hint != nil {
+		res.SetHint(opt.Hint)
+	}
+	if opt.Comment != "" {
+		res.SetComment(opt.Comment)
+	}
+	return res
+}
+
 // parsePipeline get pipeline from CallMethod result or return nil
 func parsePipeline(res []reflect.Value) MongoPipeline {
 	if len(res) > 0 {
@@ -44,6 +189,125 @@ func parsePipeline(res []reflect.Value) MongoPipeline {
 	return nil
 }
 
+// modelPipeline resolve model pipeline from option pipeline method and params
+// soft deleted records excluded for SoftDelete models unless WithTrashed or OnlyTrashed option passed
+func modelPipeline(model Model, opt MongoOption) (MongoPipeline, error) {
+	var pipeline MongoPipeline
+	if v, err := callMethod(model, opt.Pipeline, opt.Params...); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPipeline, err.Error())
+	} else {
+		pipeline = parsePipeline(v)
+	}
+	if pipeline == nil {
+		return nil, fmt.Errorf("%w: %s method should return MongoPipeline", ErrInvalidPipeline, opt.Pipeline)
+	}
+	pipeline, err := tenantPipeline(model, pipeline, opt)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := parseAsInterface[SoftDelete](model); ok && (opt.OnlyTrashed || !opt.WithTrashed) {
+		res := NewPipe()
+		if opt.OnlyTrashed {
+			res.Trashes()
+		} else {
+			res.Deleted()
+		}
+		return appendStages(res, pipeline), nil
+	}
+	return pipeline, nil
+}
+
+// trashCondition combine condition with soft delete state of SoftDelete models same as modelPipeline
+// soft deleted records excluded unless WithTrashed or OnlyTrashed option set
+func trashCondition(model Model, condition any, opt MongoOption) any {
+	if _, ok := parseAsInterface[SoftDelete](model); !ok || (opt.WithTrashed && !opt.OnlyTrashed) {
+		return condition
+	}
+	if opt.OnlyTrashed {
+		return andCondition(condition, primitive.M{"deleted_at": primitive.M{"$ne": nil}})
+	}
+	return andCondition(condition, primitive.M{"deleted_at": nil})
+}
+
+// appendStages append pipeline stages to res
+func appendStages(res MongoPipeline, pipeline MongoPipeline) MongoPipeline {
+	for _, stage := range pipeline.Build() {
+		res.Add(func(d MongoDoc) MongoDoc {
+			for _, e := range stage {
+				d.Add(e.Key, e.Value)
+			}
+			return d
+		})
+	}
+	return res
+}
+
+// hookTx run fn inside transaction on HookTx option
+func hookTx[R any](ctx context.Context, opt MongoOption, fn func(ctx context.Context) (R, error)) (R, error) {
+	if !opt.HookTx || opt.IgnoreHooks {
+		return fn(ctx)
+	}
+	var res R
+	err := WithTransaction(ctx, opt.Database.Client(), func(ctx context.Context) error {
+		var err error
+		res, err = fn(ctx)
+		return err
+	})
+	return res, err
+}
+
+// editableCondition combine batch condition with model EditableFilter
+// ErrNotEditable returned if model not implements BatchEditable and model zero value is not editable
+func editableCondition(model Model, condition any, opt MongoOption) (any, error) {
+	if opt.IgnoreGuards {
+		return condition, nil
+	}
+	if guard, ok := parseAsInterface[BatchEditable](model); ok {
+		return andCondition(condition, guard.EditableFilter()), nil
+	}
+	if !model.IsEditable() {
+		return nil, ErrNotEditable
+	}
+	return condition, nil
+}
+
+// deletableCondition combine batch condition with model DeletableFilter
+// ErrNotDeletable returned if model not implements BatchDeletable and model zero value is not deletable
+func deletableCondition(model Model, condition any, opt MongoOption) (any, error) {
+	if opt.IgnoreGuards {
+		return condition, nil
+	}
+	if guard, ok := parseAsInterface[BatchDeletable](model); ok {
+		return andCondition(condition, guard.DeletableFilter()), nil
+	}
+	if !model.IsDeletable() {
+		return nil, ErrNotDeletable
+	}
+	return condition, nil
+}
+
+// andCondition combine conditions using $and operator (ignore nil conditions)
+func andCondition(a any, b any) any {
+	if b == nil {
+		return a
+	}
+	if a == nil {
+		return b
+	}
+	return primitive.M{"$and": primitive.A{a, b}}
+}
+
+// withTrashed get option with WithTrashed enabled for loading stored record
+// cache disabled to compare changes with database version
+func withTrashed(opts ...MongoOption) MongoOption {
+	opt := optionOf(opts...)
+	opt.WithTrashed = true
+	opt.OnlyTrashed = false
+	opt.NoCache = true
+	opt.IgnoreMiddlewares = true
+	return opt
+}
+
 // callMethod call object method dynamically
 func callMethod(obj any, method string, params ...any) ([]reflect.Value, error) {
 	_type := reflect.TypeOf(obj)
@@ -74,6 +338,82 @@ func modelChecksum(v any) (string, Backup) {
 	return "", nil
 }
 
+// fillUpdateFields fill backup fields and updated_at if model changed
+// model always treated as changed if not implement Backup
+func fillUpdateFields(old any, model Model, silent bool) bool {
+	isChanged := true
+	oldCS, _ := modelChecksum(old)
+	if cs, backup := modelChecksum(model); cs != "" {
+		if cs != oldCS {
+			backup.SetChecksum(cs)
+			backup.UnMarkBackup()
+		}
+		isChanged = cs != oldCS
+	}
+	if !silent && isChanged {
+		model.FillUpdatedAt()
+	}
+	return isChanged
+}
+
+// keepTimestamps copy created_at and updated_at of old record to Timestamps model
+// prevent overriding stored timestamps with zero value of new model instance
+func keepTimestamps(old any, model Model) {
+	src, ok := parseAsInterface[Timestamps](old)
+	if !ok {
+		return
+	}
+	if dst, ok := parseAsInterface[Timestamps](model); ok {
+		dst.SetCreatedAt(src.GetCreatedAt())
+		dst.SetUpdatedAt(src.GetUpdatedAt())
+	}
+}
+
+// updateOf generate partial update of model changes compared to old record
+// full $set generated if old record not exists
+func updateOf(old any, model Model) (primitive.M, ChangeSet, error) {
+	if val := reflect.ValueOf(old); old == nil || (val.Kind() == reflect.Pointer && val.IsNil()) {
+		changes, err := Diff(nil, model)
+		return Set(model), changes, err
+	}
+
+	// cleanup copy of old record to ignore pipeline loaded fields
+	clean := reflect.New(reflect.TypeOf(old).Elem()).Interface()
+	if raw, err := bson.Marshal(old); err != nil {
+		return nil, ChangeSet{}, err
+	} else if err := bson.Unmarshal(raw, clean); err != nil {
+		return nil, ChangeSet{}, err
+	}
+	if m, ok := clean.(Model); ok {
+		m.Cleanup()
+	}
+	changes, err := Diff(clean, model)
+	return changes.Update(), changes, err
+}
+
+// revisionFilter generate model id filter (scoped to tenant) with current revision condition for revisioned models
+// model revision increased on increase mode and returned function restore model revision
+func revisionFilter(model Model, opt MongoOption, increase bool) (primitive.M, func(), bool) {
+	filter := primitive.M{"_id": model.GetID()}
+	tenantFilter(model, filter, opt)
+	rollback := func() {}
+	rev, ok := parseAsInterface[RevisionVersioning](model)
+	if !ok {
+		return filter, rollback, false
+	}
+	current := rev.GetRevision()
+	if current == 0 {
+		filter[rev.RevisionField()] = primitive.M{"$in": primitive.A{0, nil}}
+	} else {
+		filter[rev.RevisionField()] = current
+	}
+	if increase {
+		rev.SetRevision(current + 1)
+		rollback = func() { rev.SetRevision(current) }
+	}
+	return filter, rollback, true
+}
+
 // modelSafe convert v to github.com/gomig/mongoutils.Model or panic
 func modelSafe[T any](v T) Model {
 	if _v, ok := any(v).(Model); !ok {
@@ -83,6 +423,15 @@ func modelSafe[T any](v T) Model {
 	}
 }
 
+// modelsOf convert items to github.com/gomig/mongoutils.Model list or panic
+func modelsOf[T any](items []*T) []Model {
+	res := make([]Model, len(items))
+	for i, v := range items {
+		res[i] = modelSafe(v)
+	}
+	return res
+}
+
 // Get new instance of github.com/gomig/mongoutils.Model or panic if T not implement model
 func typeModelSafe[T any]() Model {
 	return modelSafe(new(T))
@@ -0,0 +1,230 @@
+package mongoutils_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type upsertPerson struct {
+	mongoutils.BaseModel `bson:",inline"`
+	Name                 string `bson:"name"`
+}
+
+func TestUpsertUpdate(t *testing.T) {
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := &upsertPerson{Name: "John"}
+	old.ID = primitive.NewObjectID()
+	old.CreatedAt = created
+	old.UpdatedAt = &updated
+
+	build := func(silent bool) map[string]any {
+		model := &upsertPerson{Name: "Jack"}
+		model.SetID(old.ID)
+		mongoutils.KeepTimestamps(old, model)
+		mongoutils.FillUpdateFields(old, model, silent)
+		update, _, err := mongoutils.UpdateOf(old, model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set, _ := update["$set"].(primitive.M)
+		if _, ok := update["$unset"]; ok {
+			t.Fatalf("unexpected $unset: %v", update)
+		}
+		return set
+	}
+
+	// silent update must keep created_at and updated_at
+	set := build(true)
+	if len(set) != 1 || set["name"] != "Jack" {
+		t.Errorf("silent update: %v", set)
+	}
+
+	// normal update must only change updated_at
+	set = build(false)
+	if _, ok := set["created_at"]; ok {
+		t.Errorf("created_at changed: %v", set)
+	}
+	if v, ok := set["updated_at"].(primitive.DateTime); !ok || !v.Time().After(updated) {
+		t.Errorf("updated_at not filled: %v", set)
+	}
+	if set["name"] != "Jack" {
+		t.Errorf("name not changed: %v", set)
+	}
+}
+
+type trashPerson struct {
+	mongoutils.BaseModel       `bson:",inline"`
+	mongoutils.SoftDeleteModel `bson:",inline"`
+	Name                       string `bson:"name"`
+}
+
+func TestTrashCondition(t *testing.T) {
+	filter := primitive.M{"name": "John"}
+	cases := []struct {
+		name     string
+		model    mongoutils.Model
+		opt      mongoutils.MongoOption
+		expected string
+	}{
+		{"not soft delete", &upsertPerson{}, mongoutils.MongoOption{}, `{"name":"John"}`},
+		{"default", &trashPerson{}, mongoutils.MongoOption{}, `{"$and":[{"name":"John"},{"deleted_at":null}]}`},
+		{"with trashed", &trashPerson{}, mongoutils.MongoOption{WithTrashed: true}, `{"name":"John"}`},
+		{"only trashed", &trashPerson{}, mongoutils.MongoOption{OnlyTrashed: true}, `{"$and":[{"name":"John"},{"deleted_at":{"$ne":null}}]}`},
+	}
+	for _, c := range cases {
+		if got, _ := pretty(mongoutils.TrashCondition(c.model, filter, c.opt)); got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestReplaceTimestamps(t *testing.T) {
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := &upsertPerson{Name: "John"}
+	old.CreatedAt = created
+	old.UpdatedAt = &updated
+
+	// upsert onto existing record must keep stored timestamps
+	model := &upsertPerson{Name: "Jack"}
+	mongoutils.ReplaceTimestamps(old, model, true)
+	if !model.CreatedAt.Equal(created) || model.UpdatedAt == nil || !model.UpdatedAt.Equal(updated) {
+		t.Errorf("upsert existing: timestamps not kept %v, %v", model.CreatedAt, model.UpdatedAt)
+	}
+
+	// replace without upsert must keep stored created_at
+	model = &upsertPerson{Name: "Jack"}
+	mongoutils.ReplaceTimestamps(old, model, false)
+	if !model.CreatedAt.Equal(created) {
+		t.Errorf("replace: created_at not kept %v", model.CreatedAt)
+	}
+
+	// upsert insert must fill created_at
+	model = &upsertPerson{Name: "Jack"}
+	mongoutils.ReplaceTimestamps[upsertPerson](nil, model, true)
+	if model.CreatedAt.IsZero() {
+		t.Error("upsert insert: created_at not filled")
+	}
+
+	// replace without match must not fill created_at
+	model = &upsertPerson{Name: "Jack"}
+	mongoutils.ReplaceTimestamps[upsertPerson](nil, model, false)
+	if !model.CreatedAt.IsZero() {
+		t.Errorf("replace: unexpected created_at %v", model.CreatedAt)
+	}
+}
+
+type lockedPerson struct {
+	mongoutils.BaseModel `bson:",inline"`
+}
+
+func (lockedPerson) IsEditable() bool {
+	return false
+}
+
+func (lockedPerson) IsDeletable() bool {
+	return false
+}
+
+type guardedPerson struct {
+	lockedPerson `bson:",inline"`
+}
+
+func (*guardedPerson) EditableFilter() any {
+	return primitive.M{"locked": false}
+}
+
+func (*guardedPerson) DeletableFilter() any {
+	return primitive.M{"system": false}
+}
+
+func TestGuardCondition(t *testing.T) {
+	filter := primitive.M{"name": "John"}
+	cases := []struct {
+		name      string
+		model     mongoutils.Model
+		opt       mongoutils.MongoOption
+		editable  string
+		deletable string
+		err       bool
+	}{
+		{"base model", &upsertPerson{}, mongoutils.MongoOption{}, `{"name":"John"}`, `{"name":"John"}`, false},
+		{"not editable and deletable", &lockedPerson{}, mongoutils.MongoOption{}, "", "", true},
+		{"ignore guards", &lockedPerson{}, mongoutils.MongoOption{IgnoreGuards: true}, `{"name":"John"}`, `{"name":"John"}`, false},
+		{"batch guard", &guardedPerson{}, mongoutils.MongoOption{}, `{"$and":[{"name":"John"},{"locked":false}]}`, `{"$and":[{"name":"John"},{"system":false}]}`, false},
+		{"batch guard ignored", &guardedPerson{}, mongoutils.MongoOption{IgnoreGuards: true}, `{"name":"John"}`, `{"name":"John"}`, false},
+	}
+	for _, c := range cases {
+		editable, err := mongoutils.EditableCondition(c.model, filter, c.opt)
+		if c.err {
+			if !errors.Is(err, mongoutils.ErrNotEditable) {
+				t.Errorf("%s: expected ErrNotEditable, got %v", c.name, err)
+			}
+		} else if got, _ := pretty(editable); err != nil || got != c.editable {
+			t.Errorf("%s: expected editable %s, got %s (%v)", c.name, c.editable, got, err)
+		}
+
+		deletable, err := mongoutils.DeletableCondition(c.model, filter, c.opt)
+		if c.err {
+			if !errors.Is(err, mongoutils.ErrNotDeletable) {
+				t.Errorf("%s: expected ErrNotDeletable, got %v", c.name, err)
+			}
+		} else if got, _ := pretty(deletable); err != nil || got != c.deletable {
+			t.Errorf("%s: expected deletable %s, got %s (%v)", c.name, c.deletable, got, err)
+		}
+	}
+
+	// base models are deletable by default
+	if !new(mongoutils.BaseModel).IsDeletable() || !new(mongoutils.EmptyModel).IsDeletable() {
+		t.Error("base models must be deletable by default")
+	}
+}
+
+type revisionPerson struct {
+	mongoutils.BaseModel     `bson:",inline"`
+	mongoutils.RevisionModel `bson:",inline"`
+	Name                     string `bson:"name"`
+}
+
+func TestRevisionFilter(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("650000000000000000000001")
+
+	// not revisioned model
+	filter, rollback, versioned := mongoutils.RevisionFilter(&upsertPerson{BaseModel: mongoutils.BaseModel{ID: id}}, mongoutils.MongoOption{}, true)
+	rollback()
+	if got, _ := pretty(filter); versioned || got != `{"_id":"650000000000000000000001"}` {
+		t.Errorf("not revisioned: %s %v", got, versioned)
+	}
+
+	cases := []struct {
+		name     string
+		revision int64
+		increase bool
+		expected string
+		next     int64
+	}{
+		{"legacy record", 0, true, `{"_id":"650000000000000000000001","revision":{"$in":[0,null]}}`, 1},
+		{"increase", 3, true, `{"_id":"650000000000000000000001","revision":3}`, 4},
+		{"delete", 3, false, `{"_id":"650000000000000000000001","revision":3}`, 3},
+	}
+	for _, c := range cases {
+		model := &revisionPerson{Name: "John"}
+		model.ID, model.Revision = id, c.revision
+		filter, rollback, versioned := mongoutils.RevisionFilter(model, mongoutils.MongoOption{}, c.increase)
+		if got, _ := pretty(filter); !versioned || got != c.expected {
+			t.Errorf("%s: expected %s, got %s (%v)", c.name, c.expected, got, versioned)
+		}
+		if model.Revision != c.next {
+			t.Errorf("%s: expected revision %d, got %d", c.name, c.next, model.Revision)
+		}
+		rollback()
+		if model.Revision != c.revision {
+			t.Errorf("%s: revision not restored %d", c.name, model.Revision)
+		}
+	}
+}
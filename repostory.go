@@ -1,11 +1,12 @@
 package mongoutils
 
 import (
+	"bytes"
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -28,50 +29,34 @@ func FindCtx[T any](
 ) ([]T, error) {
 	res := make([]T, 0)
 	model := modelSafe(new(T))
-	var pipeline MongoPipeline
-	opt := optionOf(opts...)
-	if v, err := callMethod(model, opt.Pipeline, opt.Params...); err != nil {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
 		return res, err
-	} else {
-		pipeline = parsePipeline(v)
-	}
-	if pipeline == nil {
-		return res, errors.New(opt.Pipeline + " method should return MongoPipeline!")
 	}
-	pipe := pipeline.
-		Match(filter).
-		Sort(sorts).
-		Skip(skip).
-		Limit(limit).
-		Build()
-	if opt.DebugPipe {
-		fmt.Println("=============== FIND PIPE ===============")
-		prettyLog(pipe)
-		fmt.Println("=========================================")
+	pipeline, err := modelPipeline(model, opt)
+	if err != nil {
+		return res, err
 	}
+	op := &Operation{Name: OpFind, Model: model, Filter: filter, Pipeline: pipeline, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) ([]T, error) {
+		pipe := op.Pipeline.
+			Match(op.Filter).
+			Sort(sorts).
+			Skip(skip).
+			Limit(limit).
+			Build()
 
-	if opt.DebugResult {
-		fmt.Println("============== FIND DECODE ==============")
-		if cur, err := model.Collection(opt.Database).Aggregate(ctx, pipe, AggregateOption()); err != nil {
-			fmt.Println("ERROR: " + err.Error())
+		log := logOf(OpFind, model, opt).pipe(pipe)
+		if cur, err := aggregate(ctx, collectionOf(model, opt), pipe, opt); err != nil {
+			return res, log.done(ctx, 0, err)
 		} else {
 			defer cur.Close(ctx)
-			var _res []map[string]any
-			cur.All(ctx, &_res)
-			prettyLog(_res)
-		}
-		fmt.Println("=========================================")
-	}
-
-	if cur, err := model.Collection(opt.Database).Aggregate(ctx, pipe, AggregateOption()); err != nil {
-		return res, err
-	} else {
-		defer cur.Close(ctx)
-		if err := cur.All(ctx, &res); err != nil {
-			return res, err
+			if err := cur.All(ctx, &res); err != nil {
+				return res, log.done(ctx, 0, err)
+			}
 		}
-	}
-	return res, nil
+		return res, log.result(res).done(ctx, int64(len(res)), nil)
+	})
 }
 func Find[T any](filter any, sorts any, skip int64, limit int64, opts ...MongoOption) ([]T, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -92,35 +77,28 @@ func FindRawCtx[T any](
 ) ([]T, error) {
 	res := make([]T, 0)
 	model := modelSafe(new(T))
-	option := optionOf(opts...)
-	if option.DebugPipe {
-		fmt.Println("============= FIND RAW PIPE =============")
-		prettyLog(pipeline.Build())
-		fmt.Println("=========================================")
+	option, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return res, err
+	}
+	if pipeline, err = tenantPipeline(model, pipeline, option); err != nil {
+		return res, err
 	}
+	op := &Operation{Name: OpFindRaw, Model: model, Pipeline: pipeline, Option: option}
+	return withMiddlewares(ctx, op, func(ctx context.Context) ([]T, error) {
+		pipe := rawPipelineOf(op).Build()
 
-	if option.DebugResult {
-		fmt.Println("============ FIND RAW DECODE ============")
-		if cur, err := model.Collection(option.Database).Aggregate(ctx, pipeline.Build(), AggregateOption()); err != nil {
-			fmt.Println("ERROR: " + err.Error())
+		log := logOf("find_raw", model, option).pipe(pipe)
+		if cur, err := aggregate(ctx, collectionOf(model, option), pipe, option); err != nil {
+			return res, log.done(ctx, 0, err)
 		} else {
 			defer cur.Close(ctx)
-			var _res []map[string]any
-			cur.All(ctx, &_res)
-			prettyLog(_res)
-		}
-		fmt.Println("=========================================")
-	}
-
-	if cur, err := model.Collection(option.Database).Aggregate(ctx, pipeline.Build(), AggregateOption()); err != nil {
-		return res, err
-	} else {
-		defer cur.Close(ctx)
-		if err := cur.All(ctx, &res); err != nil {
-			return res, err
+			if err := cur.All(ctx, &res); err != nil {
+				return res, log.done(ctx, 0, err)
+			}
 		}
-	}
-	return res, nil
+		return res, log.result(res).done(ctx, int64(len(res)), nil)
+	})
 }
 func FindRaw[T any](pipeline MongoPipeline, opts ...MongoOption) ([]T, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -142,47 +120,57 @@ func FindOneCtx[T any](
 ) (*T, error) {
 	res := new(T)
 	model := modelSafe(new(T))
-	var pipeline MongoPipeline
-	opt := optionOf(opts...)
-	if v, err := callMethod(model, opt.Pipeline, opt.Params...); err != nil {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
 		return res, err
-	} else {
-		pipeline = parsePipeline(v)
-	}
-	if pipeline == nil {
-		return res, errors.New(opt.Pipeline + " method should return MongoPipeline!")
 	}
-	pipe := pipeline.
-		Match(filter).
-		Sort(sorts).
-		Limit(1).
-		Build()
-	if opt.DebugPipe {
-		fmt.Println("============= FIND ONE PIPE =============")
-		prettyLog(pipe)
-		fmt.Println("=========================================")
+	pipeline, err := modelPipeline(model, opt)
+	if err != nil {
+		return res, err
 	}
+	op := &Operation{Name: OpFindOne, Model: model, Filter: filter, Pipeline: pipeline, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (*T, error) {
+		cache, collection, key, cached := cacheOf(opt), "", "", false
+		if cache != nil && !opt.NoCache && !InSession(ctx) {
+			key, cached = cacheKeyOf(op.Filter, op.Pipeline, opt)
+		}
+		var gen uint64
+		if cached {
+			collection = cacheCollectionOf(model, opt)
+			gen = cacheGeneration(collection).Load()
+			if raw, ok := cache.Get(ctx, collection, key); ok {
+				if hit := new(T); bson.Unmarshal(raw, hit) == nil {
+					return hit, logOf("find_one_cached", model, opt).result(hit).done(ctx, 1, nil)
+				}
+			}
+		}
+		pipe := op.Pipeline.
+			Match(op.Filter).
+			Sort(sorts).
+			Limit(1).
+			Build()
 
-	if cur, err := model.Collection(opt.Database).Aggregate(ctx, pipe, AggregateOption()); err != nil {
-		return res, err
-	} else {
-		defer cur.Close(ctx)
-		for cur.Next(ctx) {
-			if opt.DebugResult {
-				var _res map[string]any
-				cur.Decode(&_res)
-				fmt.Println("============ FIND ONE DECODE ============")
-				prettyLog(_res)
-				fmt.Println("=========================================")
-			}
-			if err := cur.Decode(res); err != nil {
-				return res, err
-			} else {
-				return res, nil
+		log := logOf("find_one", model, opt).pipe(pipe)
+		if cur, err := aggregate(ctx, collectionOf(model, opt), pipe, opt); err != nil {
+			return res, log.done(ctx, 0, err)
+		} else {
+			defer cur.Close(ctx)
+			for cur.Next(ctx) {
+				if err := cur.Decode(res); err != nil {
+					return res, log.done(ctx, 0, err)
+				} else {
+					if cached {
+						cacheRecord(ctx, cache, collection, key, gen, bytes.Clone(cur.Current))
+					}
+					return res, log.result(res).done(ctx, 1, nil)
+				}
+			}
+			if err := cur.Err(); err != nil {
+				return res, log.done(ctx, 0, err)
 			}
 		}
-	}
-	return nil, nil
+		return nil, log.done(ctx, 0, ErrNotFound)
+	})
 }
 func FindOne[T any](filter any, sorts any, opts ...MongoOption) (*T, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -201,32 +189,50 @@ func InsertCtx[T any](
 	v *T,
 	opts ...MongoOption,
 ) (*mongo.InsertOneResult, error) {
-	model := modelSafe(v)
-	opt := optionOf(opts...)
-	model.Cleanup()
-	model.FillCreatedAt()
-	FillBackupFields(v)
-	if !opt.IgnoreHooks {
-		model.OnInsert(ctx, opts...)
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
 	}
-	if res, err := model.Collection(opt.Database).InsertOne(ctx, model); err != nil {
-		return res, err
-	} else {
-		if opt.DebugResult {
-			fmt.Println("============= INSERT RESULT =============")
-			prettyLog(res)
-			fmt.Println("=========================================")
-		}
-		if id, ok := res.InsertedID.(primitive.ObjectID); !ok {
-			return res, errors.New("no ObjectId returned")
-		} else {
-			model.SetID(id)
+	return hookTx(ctx, opt, func(ctx context.Context) (*mongo.InsertOneResult, error) {
+		model := modelSafe(v)
+		op := &Operation{Name: OpInsert, Model: model, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.InsertOneResult, error) {
+			model.Cleanup()
+			model.FillCreatedAt()
+			FillBackupFields(v)
+			if err := stampTenant(model, opt); err != nil {
+				return nil, err
+			}
 			if !opt.IgnoreHooks {
-				model.OnInserted(ctx, opts...)
+				if err := model.OnInsert(ctx, opts...); err != nil {
+					return nil, err
+				}
 			}
-			return res, nil
-		}
-	}
+			log := logOf(OpInsert, model, opt).pipe(model)
+			res, err := retryOf(ctx, opt, false, func() (*mongo.InsertOneResult, error) {
+				return collectionOf(model, opt).InsertOne(ctx, model, insertOneOptionOf(opt))
+			})
+			if err != nil {
+				return res, log.done(ctx, 0, parseError(err))
+			} else {
+				log.result(res).done(ctx, 1, nil)
+				if id, ok := res.InsertedID.(primitive.ObjectID); !ok {
+					return res, errors.New("no ObjectId returned")
+				} else {
+					model.SetID(id)
+					if err := auditInsert(ctx, model, opt); err != nil {
+						return res, HookError{Hook: "Audit", Result: res, Err: err}
+					}
+					if !opt.IgnoreHooks {
+						if err := model.OnInserted(ctx, opts...); err != nil {
+							return res, HookError{Hook: "OnInserted", Result: res, Err: err}
+						}
+					}
+					return res, nil
+				}
+			}
+		})
+	})
 }
 func Insert[T any](v *T, opts ...MongoOption) (*mongo.InsertOneResult, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -234,6 +240,115 @@ func Insert[T any](v *T, opts ...MongoOption) (*mongo.InsertOneResult, error) {
 	return InsertCtx(ctx, v, opts...)
 }
 
+// InsertMany insert multiple records
+// on ordered mode insert stop on first failure
+// OnInserted hook only called for inserted records
+//
+// @param ctx operation context
+// @param items models
+// @param ordered insert in order and stop on first failure
+// @opts operation option
+func InsertManyCtx[T any](
+	ctx context.Context,
+	items []*T,
+	ordered bool,
+	opts ...MongoOption,
+) (*InsertManyResult, error) {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return hookTx(ctx, opt, func(ctx context.Context) (*InsertManyResult, error) {
+		op := &Operation{Name: OpInsertMany, Model: typeModelSafe[T](), Models: modelsOf(items), Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*InsertManyResult, error) {
+			result := &InsertManyResult{
+				InsertedIDs: make(map[int]primitive.ObjectID),
+				Failures:    make(map[int]error),
+				Skipped:     make([]int, 0),
+			}
+			if len(items) == 0 {
+				return result, nil
+			}
+			models := make([]Model, len(items))
+			docs := make([]any, len(items))
+			for i, v := range items {
+				model := modelSafe(v)
+				model.Cleanup()
+				model.FillCreatedAt()
+				FillBackupFields(v)
+				if err := stampTenant(model, opt); err != nil {
+					result.Failures[i] = err
+					return result, err
+				}
+				if !opt.IgnoreHooks {
+					if err := model.OnInsert(ctx, opts...); err != nil {
+						result.Failures[i] = err
+						return result, err
+					}
+				}
+				models[i] = model
+				docs[i] = model
+			}
+			log := logOf("insert_many", models[0], opt).pipe(docs)
+			res, err := retryOf(ctx, opt, false, func() (*mongo.InsertManyResult, error) {
+				return collectionOf(models[0], opt).InsertMany(ctx, docs, insertManyOptionOf(opt, ordered))
+			})
+			if res == nil {
+				return result, log.done(ctx, 0, parseError(err))
+			}
+			log.result(res).done(ctx, int64(len(res.InsertedIDs)), parseError(err))
+
+			// resolve failed records
+			var bulkErr mongo.BulkWriteException
+			if errors.As(err, &bulkErr) {
+				for _, we := range bulkErr.WriteErrors {
+					result.Failures[we.Index] = writeErrorOf(we.WriteError)
+				}
+			}
+			firstFailure := len(items)
+			if ordered {
+				for i := range result.Failures {
+					firstFailure = min(firstFailure, i)
+				}
+			}
+
+			var hookErrs, auditErrs error
+			for i, model := range models {
+				if _, failed := result.Failures[i]; failed {
+					continue
+				}
+				if i > firstFailure {
+					result.Skipped = append(result.Skipped, i)
+					continue
+				}
+				if id, ok := res.InsertedIDs[i].(primitive.ObjectID); !ok {
+					result.Failures[i] = errors.New("no ObjectId returned")
+				} else {
+					model.SetID(id)
+					result.InsertedIDs[i] = id
+					auditErrs = errors.Join(auditErrs, auditInsert(ctx, model, opt))
+					if !opt.IgnoreHooks {
+						hookErrs = errors.Join(hookErrs, model.OnInserted(ctx, opts...))
+					}
+				}
+			}
+			err = parseError(err)
+			if auditErrs != nil {
+				err = errors.Join(err, HookError{Hook: "Audit", Result: result, Err: auditErrs})
+			}
+			if hookErrs != nil {
+				err = errors.Join(err, HookError{Hook: "OnInserted", Result: result, Err: hookErrs})
+			}
+			return result, err
+		})
+	})
+}
+func InsertMany[T any](items []*T, ordered bool, opts ...MongoOption) (*InsertManyResult, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return InsertManyCtx(ctx, items, ordered, opts...)
+}
+
 // Update update one record
 //
 // @param ctx operation context
@@ -245,48 +360,202 @@ func UpdateCtx[T any](
 	v *T,
 	isSilent bool,
 	opts ...MongoOption,
+) (*mongo.UpdateResult, error) {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return hookTx(ctx, opt, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		model := modelSafe(v)
+		op := &Operation{Name: OpUpdate, Model: model, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.UpdateResult, error) {
+			old, err := FindOneCtx[T](ctx, primitive.M{"_id": model.GetID()}, nil, withTrashed(opts...))
+			if err != nil {
+				return nil, err
+			}
+			return updateModel(ctx, OpUpdate, old, model, isSilent, opt, opts...)
+		})
+	})
+}
+func Update[T any](v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return UpdateCtx(ctx, v, silent, opts...)
+}
+
+// Upsert update record matched by filter or insert new record if not exists
+// this function use FindOne to find old record
+// soft deleted records matched and restored on update unless model deleted_at set
+// insert hooks called on insert and update hooks called on update
+//
+// @param ctx operation context
+// @param filter record filter
+// @param v model
+// @param isSilent disable update meta (updated_at) on update
+// @opts operation option
+func UpsertCtx[T any](
+	ctx context.Context,
+	filter any,
+	v *T,
+	isSilent bool,
+	opts ...MongoOption,
+) (*mongo.UpdateResult, error) {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return hookTx(ctx, opt, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		op := &Operation{Name: OpUpsert, Model: modelSafe(v), Filter: filter, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.UpdateResult, error) {
+			return upsertCtx(ctx, op.Filter, v, isSilent, true, opts...)
+		})
+	})
+}
+func Upsert[T any](filter any, v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
+	ctx, cancel := MongoOperationCtx()
+	defer cancel()
+	return UpsertCtx(ctx, filter, v, silent, opts...)
+}
+
+// upsertCtx upsert record and retry as update once if record inserted by another operation
+func upsertCtx[T any](
+	ctx context.Context,
+	filter any,
+	v *T,
+	isSilent bool,
+	retry bool,
+	opts ...MongoOption,
 ) (*mongo.UpdateResult, error) {
 	model := modelSafe(v)
-	opt := optionOf(opts...)
-	old, err := FindOneCtx[T](ctx, primitive.M{"_id": model.GetID()}, nil, opts...)
+	opt, err := contextOptionOf(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
+	old, err := FindOneCtx[T](ctx, filter, nil, withTrashed(opts...))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	// Insert new record
+	if old == nil {
+		model.Cleanup()
+		model.FillCreatedAt()
+		FillBackupFields(v)
+		if model.GetID().IsZero() {
+			model.NewId()
+		}
+		if err := stampTenant(model, opt); err != nil {
+			return nil, err
+		}
+		if !opt.IgnoreHooks {
+			if err := model.OnInsert(ctx, opts...); err != nil {
+				return nil, err
+			}
+		}
+		condition, _ := tenantCondition(model, filter, opt)
+		update := primitive.M{"$setOnInsert": model}
+		log := logOf("upsert", model, opt).pipe(primitive.M{"filter": condition, "update": update})
+		res, err := retryOf(ctx, opt, true, func() (*mongo.UpdateResult, error) {
+			return collectionOf(model, opt).UpdateOne(ctx, condition, update, updateOptionOf(opt).SetUpsert(true))
+		})
+		invalidateCache(ctx, model, opt)
+		if err != nil {
+			return nil, log.done(ctx, 0, parseError(err))
+		}
+		log.result(res).done(ctx, res.UpsertedCount, nil)
+		if res.UpsertedCount == 0 {
+			// record inserted by another operation, retry as update
+			if retry {
+				return upsertCtx(ctx, filter, v, isSilent, false, opts...)
+			}
+			return res, nil
+		}
+		if id, ok := res.UpsertedID.(primitive.ObjectID); ok {
+			model.SetID(id)
+		}
+		if err := auditInsert(ctx, model, opt); err != nil {
+			return res, HookError{Hook: "Audit", Result: res, Err: err}
+		}
+		if !opt.IgnoreHooks {
+			if err := model.OnInserted(ctx, opts...); err != nil {
+				return res, HookError{Hook: "OnInserted", Result: res, Err: err}
+			}
+		}
+		return res, nil
+	}
+
+	// Update existing record
+	model.SetID(modelSafe(old).GetID())
+	if rev, ok := parseAsInterface[RevisionVersioning](model); ok {
+		rev.SetRevision(any(old).(RevisionVersioning).GetRevision())
+	}
+	keepTimestamps(old, model)
+	return updateModel(ctx, "upsert", old, model, isSilent, opt, opts...)
+}
+
+// updateModel write model changes compared to old record
+// shared by Update and update branch of Upsert to check guard, run hooks, write revisioned update and audit changes
+func updateModel[T any](
+	ctx context.Context,
+	operation string,
+	old *T,
+	model Model,
+	isSilent bool,
+	opt MongoOption,
+	opts ...MongoOption,
+) (*mongo.UpdateResult, error) {
+	if !opt.IgnoreGuards && !modelSafe(old).IsEditable() {
+		return nil, ErrNotEditable
+	}
 	// Handle model changes
 	model.Cleanup()
-	isChanged := true
-	oldCS, _ := modelChecksum(old)
-	if cs, backup := modelChecksum(v); cs != "" {
-		if cs != oldCS {
-			backup.SetChecksum(cs)
-			backup.UnMarkBackup()
-		}
-		isChanged = cs != oldCS
-	}
-	if !isSilent && isChanged {
-		model.FillUpdatedAt()
+	fillUpdateFields(old, model, isSilent)
+	if err := stampTenant(model, opt); err != nil {
+		return nil, err
 	}
 	if !opt.IgnoreHooks {
-		model.OnUpdate(ctx, opts...)
+		if err := model.OnUpdate(ctx, opts...); err != nil {
+			return nil, err
+		}
 	}
-	if res, err := model.Collection(opt.Database).UpdateByID(ctx, model.GetID(), Set(model)); err != nil {
+	filter, rollback, versioned := revisionFilter(model, opt, true)
+	update, changes, err := updateOf(old, model)
+	if err != nil {
+		rollback()
 		return nil, err
-	} else {
-		if opt.DebugResult {
-			fmt.Println("============= UPDATE RESULT =============")
-			prettyLog(res)
-			fmt.Println("=========================================")
+	}
+	if len(update) == 0 {
+		return &mongo.UpdateResult{MatchedCount: 1}, nil
+	}
+	log := logOf(operation, model, opt).pipe(primitive.M{"filter": filter, "update": update})
+	res, err := retryOf(ctx, opt, !versioned, func() (*mongo.UpdateResult, error) {
+		return collectionOf(model, opt).UpdateOne(ctx, filter, update, updateOptionOf(opt))
+	})
+	invalidateCache(ctx, model, opt)
+	if err != nil {
+		rollback()
+		return nil, log.done(ctx, 0, parseError(err))
+	}
+	log.result(res).done(ctx, res.ModifiedCount, nil)
+	if versioned && res.MatchedCount == 0 {
+		rollback()
+		return res, conflictOf(model)
+	}
+	if res.ModifiedCount > 0 {
+		if err := auditOf(ctx, model, OpUpdate, model.GetID(), changes, opt); err != nil {
+			return res, HookError{Hook: "Audit", Result: res, Err: err}
 		}
-		if res.ModifiedCount+res.UpsertedCount > 0 && !opt.IgnoreHooks {
-			model.OnUpdated(old, ctx, opts...)
+	}
+	if res.ModifiedCount+res.UpsertedCount > 0 && !opt.IgnoreHooks {
+		ctx := context.WithValue(ctx, changesKey{}, changes)
+		if err := model.OnUpdated(old, ctx, opts...); err != nil {
+			if opt.HookTx {
+				rollback()
+			}
+			return res, HookError{Hook: "OnUpdated", Result: res, Err: err}
 		}
-		return res, nil
 	}
-}
-func Update[T any](v *T, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
-	ctx, cancel := MongoOperationCtx()
-	defer cancel()
-	return UpdateCtx(ctx, v, silent, opts...)
+	return res, nil
 }
 
 // Delete delete record
@@ -299,24 +568,63 @@ func DeleteCtx[T any](
 	v *T,
 	opts ...MongoOption,
 ) (*mongo.DeleteResult, error) {
-	model := modelSafe(v)
-	opt := optionOf(opts...)
-	if !opt.IgnoreHooks {
-		model.OnDelete(ctx, opts...)
-	}
-	if res, err := model.Collection(opt.Database).DeleteOne(ctx, primitive.M{"_id": model.GetID()}); err != nil {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
 		return nil, err
-	} else {
-		if opt.DebugResult {
-			fmt.Println("============= DELETE RESULT =============")
-			prettyLog(res)
-			fmt.Println("=========================================")
-		}
-		if !opt.IgnoreHooks {
-			model.OnDeleted(ctx, opts...)
-		}
-		return res, nil
 	}
+	return hookTx(ctx, opt, func(ctx context.Context) (*mongo.DeleteResult, error) {
+		model := modelSafe(v)
+		op := &Operation{Name: OpDelete, Model: model, Option: opt}
+		return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.DeleteResult, error) {
+			if !opt.IgnoreGuards {
+				old, err := FindOneCtx[T](ctx, primitive.M{"_id": model.GetID()}, nil, withTrashed(opts...))
+				if err != nil {
+					return nil, err
+				}
+				if !modelSafe(old).IsDeletable() {
+					return nil, ErrNotDeletable
+				}
+			}
+			if err := stampTenant(model, opt); err != nil {
+				return nil, err
+			}
+			if !opt.IgnoreHooks {
+				if err := model.OnDelete(ctx, opts...); err != nil {
+					return nil, err
+				}
+			}
+			snapshot, err := auditSnapshot(ctx, model, opt)
+			if err != nil {
+				return nil, err
+			}
+			filter, _, versioned := revisionFilter(model, opt, false)
+			log := logOf(OpDelete, model, opt).pipe(filter)
+			res, err := retryOf(ctx, opt, !versioned, func() (*mongo.DeleteResult, error) {
+				return collectionOf(model, opt).DeleteOne(ctx, filter, deleteOptionOf(opt))
+			})
+			invalidateCache(ctx, model, opt)
+			if err != nil {
+				return nil, log.done(ctx, 0, parseError(err))
+			} else {
+				log.result(res).done(ctx, res.DeletedCount, nil)
+				if versioned && res.DeletedCount == 0 {
+					return res, conflictOf(model)
+				}
+				if res.DeletedCount == 0 {
+					return res, ErrNotFound
+				}
+				if err := auditDelete(ctx, model, OpDelete, snapshot, opt); err != nil {
+					return res, HookError{Hook: "Audit", Result: res, Err: err}
+				}
+				if !opt.IgnoreHooks {
+					if err := model.OnDeleted(ctx, opts...); err != nil {
+						return res, HookError{Hook: "OnDeleted", Result: res, Err: err}
+					}
+				}
+				return res, nil
+			}
+		})
+	})
 }
 func Delete[T any](v *T, opts ...MongoOption) (*mongo.DeleteResult, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -335,48 +643,42 @@ func CountCtx[T any](
 	opts ...MongoOption,
 ) (int64, error) {
 	model := typeModelSafe[T]()
-	var pipeline MongoPipeline
-	opt := optionOf(opts...)
-	if v, err := callMethod(model, opt.Pipeline, opt.Params...); err != nil {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
 		return 0, err
-	} else {
-		pipeline = parsePipeline(v)
 	}
-	if pipeline == nil {
-		return 0, errors.New(opt.Pipeline + " method should return MongoPipeline!")
-	}
-	pipe := pipeline.
-		Match(filter).
-		Add(func(d MongoDoc) MongoDoc {
-			return d.Add("$count", "count")
-		}).
-		Build()
-	if opt.DebugPipe {
-		fmt.Println("=============== COUNT PIPE ===============")
-		prettyLog(pipe)
-		fmt.Println("==========================================")
-	}
-	if cur, err := model.Collection(opt.Database).Aggregate(ctx, pipe, AggregateOption()); err != nil {
+	pipeline, err := modelPipeline(model, opt)
+	if err != nil {
 		return 0, err
-	} else {
-		defer cur.Close(ctx)
-		for cur.Next(ctx) {
-			if opt.DebugResult {
-				var _res map[string]any
-				cur.Decode(&_res)
-				fmt.Println("=============== COUNT DECODE ===============")
-				prettyLog(_res)
-				fmt.Println("============================================")
-			}
-			rec := new(countResult)
-			if err := cur.Decode(rec); err != nil {
-				return 0, err
-			} else {
-				return rec.Count, nil
+	}
+	op := &Operation{Name: OpCount, Model: model, Filter: filter, Pipeline: pipeline, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (int64, error) {
+		pipe := op.Pipeline.
+			Match(op.Filter).
+			Add(func(d MongoDoc) MongoDoc {
+				return d.Add("$count", "count")
+			}).
+			Build()
+
+		log := logOf("count", model, opt).pipe(pipe)
+		if cur, err := aggregate(ctx, collectionOf(model, opt), pipe, opt); err != nil {
+			return 0, log.done(ctx, 0, err)
+		} else {
+			defer cur.Close(ctx)
+			for cur.Next(ctx) {
+				rec := new(countResult)
+				if err := cur.Decode(rec); err != nil {
+					return 0, log.done(ctx, 0, err)
+				} else {
+					return rec.Count, log.result(rec).done(ctx, rec.Count, nil)
+				}
+			}
+			if err := cur.Err(); err != nil {
+				return 0, log.done(ctx, 0, err)
 			}
 		}
-	}
-	return 0, nil
+		return 0, log.done(ctx, 0, nil)
+	})
 }
 func Count[T any](filter any, opts ...MongoOption) (int64, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -396,34 +698,38 @@ func CountRawCtx[T any](
 	opts ...MongoOption,
 ) (int64, error) {
 	model := typeModelSafe[T]()
-	option := optionOf(opts...)
-	pipeline.Add(func(d MongoDoc) MongoDoc { return d.Add("$count", "count") })
-	if option.DebugPipe {
-		fmt.Println("=============== COUNT PIPE ===============")
-		prettyLog(pipeline.Build())
-		fmt.Println("==========================================")
+	option, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return 0, err
 	}
-	if cur, err := model.Collection(option.Database).Aggregate(ctx, pipeline.Build(), AggregateOption()); err != nil {
+	if pipeline, err = tenantPipeline(model, pipeline, option); err != nil {
 		return 0, err
-	} else {
-		defer cur.Close(ctx)
-		for cur.Next(ctx) {
-			if option.DebugResult {
-				var _res map[string]any
-				cur.Decode(&_res)
-				fmt.Println("=============== COUNT DECODE ===============")
-				prettyLog(_res)
-				fmt.Println("============================================")
-			}
-			rec := new(countResult)
-			if err := cur.Decode(rec); err != nil {
-				return 0, err
-			} else {
-				return rec.Count, nil
+	}
+	op := &Operation{Name: OpCountRaw, Model: model, Pipeline: pipeline, Option: option}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (int64, error) {
+		pipe := rawPipelineOf(op).
+			Add(func(d MongoDoc) MongoDoc { return d.Add("$count", "count") }).
+			Build()
+
+		log := logOf("count_raw", model, option).pipe(pipe)
+		if cur, err := aggregate(ctx, collectionOf(model, option), pipe, option); err != nil {
+			return 0, log.done(ctx, 0, err)
+		} else {
+			defer cur.Close(ctx)
+			for cur.Next(ctx) {
+				rec := new(countResult)
+				if err := cur.Decode(rec); err != nil {
+					return 0, log.done(ctx, 0, err)
+				} else {
+					return rec.Count, log.result(rec).done(ctx, rec.Count, nil)
+				}
+			}
+			if err := cur.Err(); err != nil {
+				return 0, log.done(ctx, 0, err)
 			}
 		}
-	}
-	return 0, nil
+		return 0, log.done(ctx, 0, nil)
+	})
 }
 func CountRaw[T any](filter any, opts ...MongoOption) (int64, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -444,17 +750,38 @@ func BatchUpdateCtx[T any](
 	opts ...MongoOption,
 ) (*mongo.UpdateResult, error) {
 	model := typeModelSafe[T]()
-	opt := optionOf(opts...)
-	if res, err := model.Collection(opt.Database).UpdateMany(ctx, condition, updates); err != nil {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
 		return nil, err
-	} else {
-		if opt.DebugResult {
-			fmt.Println("========== BATCH UPDATE RESULT ==========")
-			prettyLog(res)
-			fmt.Println("=========================================")
-		}
-		return res, nil
 	}
+	op := &Operation{Name: OpBatchUpdate, Model: model, Filter: condition, Update: updates, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		condition, err := editableCondition(model, op.Filter, opt)
+		if err != nil {
+			return nil, err
+		}
+		if condition, err = tenantCondition(model, condition, opt); err != nil {
+			return nil, err
+		}
+		audit, condition, err := beginBatchAudit(ctx, model, condition, opt)
+		if err != nil {
+			return nil, err
+		}
+		log := logOf(OpBatchUpdate, model, opt).pipe(primitive.M{"filter": condition, "update": op.Update})
+		res, err := retryOf(ctx, opt, false, func() (*mongo.UpdateResult, error) {
+			return collectionOf(model, opt).UpdateMany(ctx, condition, op.Update, updateOptionOf(opt))
+		})
+		invalidateCache(ctx, model, opt)
+		if err != nil {
+			return nil, log.done(ctx, 0, parseError(err))
+		} else {
+			log.result(res).done(ctx, res.ModifiedCount, nil)
+			if err := audit.commit(ctx, OpBatchUpdate, op.Update, opt); err != nil {
+				return res, HookError{Hook: "Audit", Result: res, Err: err}
+			}
+			return res, nil
+		}
+	})
 }
 func BatchUpdate[T any](condition any, updates any, opts ...MongoOption) (*mongo.UpdateResult, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -477,20 +804,46 @@ func PatchCtx[T any](
 	opts ...MongoOption,
 ) (*mongo.UpdateResult, error) {
 	model := typeModelSafe[T]()
-	opt := optionOf(opts...)
-	if !silent {
-		data["updated_at"] = time.Now().UTC()
-	}
-	if res, err := model.Collection(opt.Database).UpdateMany(ctx, condition, Set(data)); err != nil {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
 		return nil, err
-	} else {
-		if opt.DebugResult {
-			fmt.Println("============== PATCH RESULT ==============")
-			prettyLog(res)
-			fmt.Println("==========================================")
-		}
-		return res, nil
 	}
+	op := &Operation{Name: OpPatch, Model: model, Filter: condition, Update: data, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		condition, err := editableCondition(model, op.Filter, opt)
+		if err != nil {
+			return nil, err
+		}
+		if condition, err = tenantCondition(model, condition, opt); err != nil {
+			return nil, err
+		}
+		audit, condition, err := beginBatchAudit(ctx, model, condition, opt)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := op.Update.(primitive.M)
+		if !ok {
+			return nil, errors.New("patch data must be primitive.M")
+		}
+		if !silent {
+			data["updated_at"] = time.Now().UTC()
+		}
+		update := Set(data)
+		log := logOf(OpPatch, model, opt).pipe(primitive.M{"filter": condition, "update": update})
+		res, err := retryOf(ctx, opt, true, func() (*mongo.UpdateResult, error) {
+			return collectionOf(model, opt).UpdateMany(ctx, condition, update, updateOptionOf(opt))
+		})
+		invalidateCache(ctx, model, opt)
+		if err != nil {
+			return nil, log.done(ctx, 0, parseError(err))
+		} else {
+			log.result(res).done(ctx, res.ModifiedCount, nil)
+			if err := audit.commit(ctx, OpPatch, update, opt); err != nil {
+				return res, HookError{Hook: "Audit", Result: res, Err: err}
+			}
+			return res, nil
+		}
+	})
 }
 func Patch[T any](condition any, data primitive.M, silent bool, opts ...MongoOption) (*mongo.UpdateResult, error) {
 	ctx, cancel := MongoOperationCtx()
@@ -513,17 +866,39 @@ func IncrementCtx[T any](
 	opts ...MongoOption,
 ) (*mongo.UpdateResult, error) {
 	model := typeModelSafe[T]()
-	opt := optionOf(opts...)
-	if res, err := model.Collection(opt.Database).UpdateMany(ctx, condition, primitive.M{"$inc": data}); err != nil {
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
 		return nil, err
-	} else {
-		if opt.DebugResult {
-			fmt.Println("============ INCREMENT RESULT ============")
-			prettyLog(res)
-			fmt.Println("==========================================")
-		}
-		return res, nil
 	}
+	op := &Operation{Name: OpIncrement, Model: model, Filter: condition, Update: data, Option: opt}
+	return withMiddlewares(ctx, op, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		condition, err := editableCondition(model, op.Filter, opt)
+		if err != nil {
+			return nil, err
+		}
+		if condition, err = tenantCondition(model, condition, opt); err != nil {
+			return nil, err
+		}
+		audit, condition, err := beginBatchAudit(ctx, model, condition, opt)
+		if err != nil {
+			return nil, err
+		}
+		update := primitive.M{"$inc": op.Update}
+		log := logOf(OpIncrement, model, opt).pipe(primitive.M{"filter": condition, "update": update})
+		res, err := retryOf(ctx, opt, false, func() (*mongo.UpdateResult, error) {
+			return collectionOf(model, opt).UpdateMany(ctx, condition, update, updateOptionOf(opt))
+		})
+		invalidateCache(ctx, model, opt)
+		if err != nil {
+			return nil, log.done(ctx, 0, parseError(err))
+		} else {
+			log.result(res).done(ctx, res.ModifiedCount, nil)
+			if err := audit.commit(ctx, OpIncrement, update, opt); err != nil {
+				return res, HookError{Hook: "Audit", Result: res, Err: err}
+			}
+			return res, nil
+		}
+	})
 }
 func Increment[T any](condition any, data any, opts ...MongoOption) (*mongo.UpdateResult, error) {
 	ctx, cancel := MongoOperationCtx()
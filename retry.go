@@ -0,0 +1,111 @@
+package mongoutils
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RetryPolicy retry policy of repository operations on transient errors
+// reads and idempotent writes (Update, Upsert, Delete, Patch, ...) retried
+// non idempotent writes (Insert, InsertMany, BatchUpdate, Increment, ...) only retried if AllowNonIdempotent set
+// operations inside transaction never retried, transaction retried by WithTransaction
+type RetryPolicy struct {
+	// MaxAttempts max attempts count including first attempt (retry disabled on <= 1)
+	MaxAttempts int
+	// Backoff first retry delay, doubled on each retry
+	Backoff time.Duration
+	// MaxBackoff max retry delay (ignored on 0)
+	MaxBackoff time.Duration
+	// AllowNonIdempotent retry non idempotent writes
+	AllowNonIdempotent bool
+	// Retryable custom error classifier (IsRetryable used on nil)
+	Retryable func(err error) bool
+}
+
+// globalRetry retry policy used if no policy passed to option
+var globalRetry RetryPolicy
+
+// SetRetryPolicy set global repository retry policy
+// pass zero policy to disable retry
+func SetRetryPolicy(policy RetryPolicy) {
+	globalRetry = policy
+}
+
+// retryableCodes server error codes of primary step down and shutdown
+var retryableCodes = []int{6, 7, 89, 91, 189, 262, 9001, 10107, 11600, 11602, 13435, 13436}
+
+// IsRetryable check if error is transient
+// network errors, errors with RetryableWriteError label and primary step down errors are transient
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var labeled mongo.LabeledError
+	if errors.As(err, &labeled) && labeled.HasErrorLabel("RetryableWriteError") {
+		return true
+	}
+	var server mongo.ServerError
+	if errors.As(err, &server) {
+		for _, code := range retryableCodes {
+			if server.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryOf run database call and retry on transient errors based on option or global retry policy
+func retryOf[R any](ctx context.Context, opt MongoOption, idempotent bool, fn func() (R, error)) (R, error) {
+	policy := globalRetry
+	if opt.Retry != nil {
+		policy = *opt.Retry
+	}
+	res, err := fn()
+	if policy.MaxAttempts <= 1 || (!idempotent && !policy.AllowNonIdempotent) || InSession(ctx) {
+		return res, err
+	}
+	for attempt := 1; attempt < policy.MaxAttempts && err != nil && policy.retryable(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return res, err
+		case <-time.After(policy.delay(attempt)):
+		}
+		res, err = fn()
+	}
+	return res, err
+}
+
+// retryable classify error using custom classifier or IsRetryable
+func (policy RetryPolicy) retryable(err error) bool {
+	if policy.Retryable != nil {
+		return policy.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// delay get exponential backoff delay of retry with jitter (half of delay randomized)
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	res := policy.Backoff << (attempt - 1)
+	if policy.MaxBackoff > 0 && (res > policy.MaxBackoff || res <= 0) {
+		res = policy.MaxBackoff
+	}
+	if half := int64(res / 2); half > 0 {
+		return time.Duration(half + rand.Int63n(half+1))
+	}
+	return res
+}
+
+// aggregate run aggregate with retry
+func aggregate(ctx context.Context, coll *mongo.Collection, pipe any, opt MongoOption) (*mongo.Cursor, error) {
+	return retryOf(ctx, opt, true, func() (*mongo.Cursor, error) {
+		return coll.Aggregate(ctx, pipe, aggregateOptionOf(opt))
+	})
+}
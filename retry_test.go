@@ -0,0 +1,33 @@
+package mongoutils_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"plain", errors.New("failed"), false},
+		{"label", mongo.CommandError{Code: 1, Labels: []string{"RetryableWriteError"}}, true},
+		{"step down", mongo.CommandError{Code: 189}, true},
+		{"wrapped not primary", fmt.Errorf("update: %w", mongo.CommandError{Code: 10107}), true},
+		{"duplicate", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, false},
+		{"network", mongo.CommandError{Labels: []string{"NetworkError"}}, true},
+	}
+	for _, c := range cases {
+		if got := mongoutils.IsRetryable(c.err); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
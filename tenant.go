@@ -0,0 +1,120 @@
+package mongoutils
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type tenantKey struct{}
+
+// MultiTenant model shared between tenants in single collection
+// reads filtered by tenant field and tenant field stamped and enforced on writes
+type MultiTenant interface {
+	// TenantField get tenant field name (e.g. tenant_id)
+	TenantField() string
+	// GetTenant get model tenant
+	GetTenant() string
+	// SetTenant set model tenant
+	SetTenant(tenant string)
+}
+
+// TenantResolver resolve operation database from context
+// tenant is context tenant (empty if not attached)
+type TenantResolver func(ctx context.Context, tenant string) (*mongo.Database, error)
+
+// tenantResolver database resolver used if no database passed to option
+var tenantResolver TenantResolver
+
+// SetTenantResolver set global tenant database resolver
+// resolver called for operations without Database option
+// pass nil to disable resolver
+func SetTenantResolver(resolver TenantResolver) {
+	tenantResolver = resolver
+}
+
+// WithTenant attach operation tenant to context
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantOf get tenant attached to context (empty if not attached)
+func TenantOf(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// contextOptionOf get option of dynamic params with tenant and database resolved from context
+func contextOptionOf(ctx context.Context, opts ...MongoOption) (MongoOption, error) {
+	opt := optionOf(opts...)
+	if opt.Tenant == "" {
+		opt.Tenant = TenantOf(ctx)
+	}
+	if opt.Database == nil && tenantResolver != nil {
+		db, err := tenantResolver(ctx, opt.Tenant)
+		if err != nil {
+			return opt, err
+		}
+		if db == nil {
+			return opt, ErrTenantRequired
+		}
+		opt.Database = db
+	}
+	return opt, nil
+}
+
+// tenantOf get tenant field and value of MultiTenant model
+// ok is false if model not MultiTenant or tenant ignored
+func tenantOf(model Model, opt MongoOption) (field string, tenant string, ok bool, err error) {
+	mt, isTenant := parseAsInterface[MultiTenant](model)
+	if !isTenant || opt.IgnoreTenant {
+		return "", "", false, nil
+	}
+	if opt.Tenant == "" {
+		return "", "", false, ErrTenantRequired
+	}
+	return mt.TenantField(), opt.Tenant, true, nil
+}
+
+// tenantCondition combine condition with tenant filter of MultiTenant models
+func tenantCondition(model Model, condition any, opt MongoOption) (any, error) {
+	field, tenant, ok, err := tenantOf(model, opt)
+	if !ok {
+		return condition, err
+	}
+	return andCondition(condition, primitive.M{field: tenant}), nil
+}
+
+// tenantFilter add tenant to document filter of MultiTenant models
+// tenant must validated by stampTenant before
+func tenantFilter(model Model, filter primitive.M, opt MongoOption) {
+	if field, tenant, ok, _ := tenantOf(model, opt); ok {
+		filter[field] = tenant
+	}
+}
+
+// tenantPipeline prepend tenant match stage to pipeline of MultiTenant models
+func tenantPipeline(model Model, pipeline MongoPipeline, opt MongoOption) (MongoPipeline, error) {
+	field, tenant, ok, err := tenantOf(model, opt)
+	if !ok {
+		return pipeline, err
+	}
+	return appendStages(NewPipe().Match(primitive.M{field: tenant}), pipeline), nil
+}
+
+// stampTenant set tenant of MultiTenant model or check model tenant
+// ErrTenantMismatch returned if model belongs to other tenant
+func stampTenant(model Model, opt MongoOption) error {
+	_, tenant, ok, err := tenantOf(model, opt)
+	if !ok {
+		return err
+	}
+	mt, _ := parseAsInterface[MultiTenant](model)
+	if current := mt.GetTenant(); current == "" {
+		mt.SetTenant(tenant)
+	} else if current != tenant {
+		return ErrTenantMismatch
+	}
+	return nil
+}
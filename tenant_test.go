@@ -0,0 +1,90 @@
+package mongoutils_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestContextOptionOf(t *testing.T) {
+	defer mongoutils.SetTenantResolver(nil)
+	ctx := mongoutils.WithTenant(context.Background(), "acme")
+
+	// context tenant used if option tenant not set
+	if opt, err := mongoutils.ContextOptionOf(ctx); err != nil || opt.Tenant != "acme" || opt.Pipeline != "Pipeline" {
+		t.Errorf("context tenant not resolved: %+v, %v", opt, err)
+	}
+	if opt, _ := mongoutils.ContextOptionOf(ctx, mongoutils.MongoOption{Tenant: "globex"}); opt.Tenant != "globex" {
+		t.Errorf("option tenant overridden: %s", opt.Tenant)
+	}
+
+	// resolver called for option without database
+	db, other := new(mongo.Database), new(mongo.Database)
+	mongoutils.SetTenantResolver(func(ctx context.Context, tenant string) (*mongo.Database, error) {
+		if tenant == "acme" {
+			return db, nil
+		}
+		return nil, nil
+	})
+	if opt, err := mongoutils.ContextOptionOf(ctx); err != nil || opt.Database != db {
+		t.Errorf("tenant database not resolved: %v", err)
+	}
+	if opt, err := mongoutils.ContextOptionOf(ctx, mongoutils.MongoOption{Database: other}); err != nil || opt.Database != other {
+		t.Errorf("option database overridden: %v", err)
+	}
+	if _, err := mongoutils.ContextOptionOf(context.Background()); !errors.Is(err, mongoutils.ErrTenantRequired) {
+		t.Errorf("expected tenant required, got %v", err)
+	}
+}
+
+func TestStampTenant(t *testing.T) {
+	acme := mongoutils.MongoOption{Tenant: "acme"}
+
+	// tenant stamped on empty model
+	item := new(watchTenantItem)
+	if err := mongoutils.StampTenant(item, acme); err != nil || item.Tenant != "acme" {
+		t.Errorf("tenant not stamped: %s, %v", item.Tenant, err)
+	}
+	if err := mongoutils.StampTenant(item, acme); err != nil {
+		t.Errorf("same tenant rejected: %v", err)
+	}
+
+	// other tenant model rejected
+	item = &watchTenantItem{Tenant: "globex"}
+	if err := mongoutils.StampTenant(item, acme); !errors.Is(err, mongoutils.ErrTenantMismatch) {
+		t.Errorf("expected tenant mismatch, got %v", err)
+	}
+
+	// tenant required
+	if err := mongoutils.StampTenant(new(watchTenantItem), mongoutils.MongoOption{}); !errors.Is(err, mongoutils.ErrTenantRequired) {
+		t.Errorf("expected tenant required, got %v", err)
+	}
+
+	// ignored tenant and non tenant model
+	item = &watchTenantItem{Tenant: "globex"}
+	if err := mongoutils.StampTenant(item, mongoutils.MongoOption{Tenant: "acme", IgnoreTenant: true}); err != nil || item.Tenant != "globex" {
+		t.Errorf("ignored tenant checked: %s, %v", item.Tenant, err)
+	}
+	if err := mongoutils.StampTenant(new(watchItem), mongoutils.MongoOption{}); err != nil {
+		t.Errorf("non tenant model checked: %v", err)
+	}
+}
+
+func TestTenantFilter(t *testing.T) {
+	filter := primitive.M{"_id": 1}
+	mongoutils.TenantFilter(new(watchTenantItem), filter, mongoutils.MongoOption{Tenant: "acme"})
+	if filter["tenant_id"] != "acme" {
+		t.Errorf("tenant not added: %v", filter)
+	}
+
+	filter = primitive.M{"_id": 1}
+	mongoutils.TenantFilter(new(watchTenantItem), filter, mongoutils.MongoOption{Tenant: "acme", IgnoreTenant: true})
+	mongoutils.TenantFilter(new(watchItem), filter, mongoutils.MongoOption{Tenant: "acme"})
+	if len(filter) != 1 {
+		t.Errorf("tenant added for ignored tenant or non tenant model: %v", filter)
+	}
+}
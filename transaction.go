@@ -0,0 +1,48 @@
+package mongoutils
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WithTransaction run fn inside transaction and commit on success
+// transaction retried on TransientTransactionError and commit retried on UnknownTransactionCommitResult error label
+// pass fn ctx to repository functions and hooks to participate in transaction
+// fn joins parent transaction if ctx already contains session of client
+// TxOption used if no option passed
+//
+// @param ctx parent context
+// @param client mongo client to start session
+// @param fn transaction body (may called multiple times on retry)
+// @opts transaction option
+func WithTransaction(
+	ctx context.Context,
+	client *mongo.Client,
+	fn func(ctx context.Context) error,
+	opts ...*options.TransactionOptions,
+) error {
+	if session := mongo.SessionFromContext(ctx); session != nil && session.Client() == client {
+		return fn(ctx)
+	}
+	if len(opts) == 0 {
+		opts = append(opts, TxOption())
+	}
+	// invalidate cache of changed collections again after transaction end
+	pending := new(pendingInvalidation)
+	defer pending.flush(ctx)
+	ctx = context.WithValue(ctx, pendingKey{}, pending)
+	return client.UseSession(ctx, func(sc mongo.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (any, error) {
+			return nil, fn(sc)
+		}, opts...)
+		return err
+	})
+}
+
+// InSession check if context contains mongo session
+// repository functions called with this context participate in session transaction
+func InSession(ctx context.Context) bool {
+	return mongo.SessionFromContext(ctx) != nil
+}
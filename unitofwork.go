@@ -0,0 +1,43 @@
+package mongoutils
+
+import "context"
+
+// UnitOperation unit of work operation type
+type UnitOperation string
+
+const (
+	UnitInsert UnitOperation = "insert"
+	UnitUpdate UnitOperation = "update"
+	UnitDelete UnitOperation = "delete"
+)
+
+// UnitOfWork collect model changes and persist them using BulkWrite per collection
+type UnitOfWork interface {
+	// Insert register model for insert
+	// model id generated if not set
+	Insert(v Model) UnitOfWork
+	// Update register model for update
+	// silent disable update meta (updated_at)
+	Update(v Model, silent bool) UnitOfWork
+	// Delete register model for delete
+	Delete(v Model) UnitOfWork
+	// Len get registered models count
+	Len() int
+	// Commit write registered models
+	// on ordered mode models written in registration order and write stop on first failure
+	// update or delete of missing or changed record is failure and stop next writes
+	Commit(ctx context.Context, ordered bool) ([]UnitResult, error)
+	// CommitTx write registered models in ordered mode inside transaction
+	// no model written and post hooks not called on failure
+	CommitTx(ctx context.Context) ([]UnitResult, error)
+}
+
+// UnitResult unit of work model result
+type UnitResult struct {
+	Operation UnitOperation
+	Model     Model
+	// Succeed model written to database
+	Succeed bool
+	// Err model write error (nil for skipped models on ordered mode)
+	Err error
+}
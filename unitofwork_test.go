@@ -0,0 +1,92 @@
+package mongoutils_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUnitGroups(t *testing.T) {
+	names := []string{"users", "users", "posts", "users", "posts"}
+
+	// ordered keep registration order
+	if v := mongoutils.UnitGroups(names, true); !reflect.DeepEqual(v, [][]int{{0, 1}, {2}, {3}, {4}}) {
+		t.Fatalf("fail ordered groups: %v", v)
+	}
+
+	// unordered group by collection
+	if v := mongoutils.UnitGroups(names, false); !reflect.DeepEqual(v, [][]int{{0, 1, 3}, {2, 4}}) {
+		t.Fatalf("fail unordered groups: %v", v)
+	}
+}
+
+func TestUnitChunks(t *testing.T) {
+	ops := []mongoutils.UnitOperation{
+		mongoutils.UnitInsert, mongoutils.UnitUpdate, mongoutils.UnitInsert,
+		mongoutils.UnitInsert, mongoutils.UnitDelete, mongoutils.UnitInsert,
+	}
+
+	// ordered split after update and delete
+	if v := mongoutils.UnitChunks(ops, true); !reflect.DeepEqual(v, [][2]int{{0, 2}, {2, 5}, {5, 6}}) {
+		t.Fatalf("fail ordered chunks: %v", v)
+	}
+
+	// unordered single chunk
+	if v := mongoutils.UnitChunks(ops, false); !reflect.DeepEqual(v, [][2]int{{0, 6}}) {
+		t.Fatalf("fail unordered chunks: %v", v)
+	}
+}
+
+func TestBulkResults(t *testing.T) {
+	bulkErr := mongo.BulkWriteException{
+		WriteErrors: []mongo.BulkWriteError{
+			{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "E11000 duplicate key error"}},
+			{WriteError: mongo.WriteError{Index: 3, Code: 121, Message: "validation failed"}},
+		},
+	}
+
+	// ordered stop on first failure
+	succeed, failures := mongoutils.BulkResultsOf(4, true, bulkErr)
+	if !reflect.DeepEqual(succeed, []bool{true, false, false, false}) {
+		t.Fatalf("fail ordered succeed: %v", succeed)
+	}
+	if failures[0] != nil || failures[2] != nil {
+		t.Fatal("fail ordered skipped writes")
+	}
+	var dupErr mongoutils.DuplicateKeyError
+	if !errors.As(failures[1], &dupErr) {
+		t.Fatalf("fail duplicate key mapping: %v", failures[1])
+	}
+
+	// unordered run all writes
+	succeed, failures = mongoutils.BulkResultsOf(4, false, bulkErr)
+	if !reflect.DeepEqual(succeed, []bool{true, false, true, false}) {
+		t.Fatalf("fail unordered succeed: %v", succeed)
+	}
+	if failures[3] == nil || failures[2] != nil {
+		t.Fatal("fail unordered failures")
+	}
+
+	// non bulk error fail all writes
+	err := errors.New("network error")
+	succeed, failures = mongoutils.BulkResultsOf(2, false, err)
+	if succeed[0] || succeed[1] || failures[0] != err || failures[1] != err {
+		t.Fatal("fail non bulk error")
+	}
+
+	// write concern error fail all writes
+	wcErr := mongo.BulkWriteException{WriteConcernError: &mongo.WriteConcernError{Code: 64}}
+	succeed, _ = mongoutils.BulkResultsOf(2, false, wcErr)
+	if succeed[0] || succeed[1] {
+		t.Fatal("fail write concern error")
+	}
+
+	// succeed
+	succeed, failures = mongoutils.BulkResultsOf(2, true, nil)
+	if !succeed[0] || !succeed[1] || failures[0] != nil || failures[1] != nil {
+		t.Fatal("fail succeed writes")
+	}
+}
@@ -0,0 +1,607 @@
+package mongoutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type unitEntry struct {
+	op       UnitOperation
+	model    Model
+	silent   bool
+	old      any
+	changes  ChangeSet
+	rollback func()
+	prepared bool
+	write    mongo.WriteModel
+	err      error
+}
+
+type unitOfWork struct {
+	opts    []MongoOption
+	entries []unitEntry
+}
+
+func (uow *unitOfWork) Insert(v Model) UnitOfWork {
+	uow.entries = append(uow.entries, unitEntry{op: UnitInsert, model: v})
+	return uow
+}
+
+func (uow *unitOfWork) Update(v Model, silent bool) UnitOfWork {
+	uow.entries = append(uow.entries, unitEntry{op: UnitUpdate, model: v, silent: silent})
+	return uow
+}
+
+func (uow *unitOfWork) Delete(v Model) UnitOfWork {
+	uow.entries = append(uow.entries, unitEntry{op: UnitDelete, model: v})
+	return uow
+}
+
+func (uow *unitOfWork) Len() int {
+	return len(uow.entries)
+}
+
+func (uow *unitOfWork) Commit(ctx context.Context, ordered bool) ([]UnitResult, error) {
+	uow.reset()
+	results, err := uow.write(ctx, ordered)
+	uow.rollbackFailed(results)
+	if hookErr := uow.afterWrite(ctx, results); hookErr != nil {
+		if err != nil {
+			return results, errors.Join(err, hookErr)
+		}
+		return results, hookErr
+	}
+	return results, err
+}
+
+func (uow *unitOfWork) CommitTx(ctx context.Context) ([]UnitResult, error) {
+	uow.reset()
+	opt, err := contextOptionOf(ctx, uow.opts...)
+	if err != nil {
+		return uow.failed(err), err
+	}
+	var results []UnitResult
+	err = WithTransaction(ctx, opt.Database.Client(), func(ctx context.Context) error {
+		var err error
+		if results, err = uow.write(ctx, true); err != nil {
+			return err
+		}
+		if opt.HookTx {
+			return uow.afterWrite(ctx, results)
+		}
+		return nil
+	})
+	if err != nil {
+		if results == nil {
+			results = uow.results()
+		}
+		for i := range results {
+			results[i].Succeed = false
+			if results[i].Err == nil {
+				results[i].Err = err
+			}
+		}
+		uow.rollbackFailed(results)
+		return results, err
+	}
+	if !opt.HookTx {
+		return results, uow.afterWrite(ctx, results)
+	}
+	return results, nil
+}
+
+// results generate empty results of entries
+func (uow *unitOfWork) results() []UnitResult {
+	results := make([]UnitResult, len(uow.entries))
+	for i, e := range uow.entries {
+		results[i] = UnitResult{Operation: e.op, Model: e.model}
+	}
+	return results
+}
+
+// failed generate failed results of entries
+func (uow *unitOfWork) failed(err error) []UnitResult {
+	results := uow.results()
+	for i := range results {
+		results[i].Err = err
+	}
+	return results
+}
+
+// reset clear loaded and prepared state of previous commit
+func (uow *unitOfWork) reset() {
+	for i, e := range uow.entries {
+		uow.entries[i] = unitEntry{op: e.op, model: e.model, silent: e.silent}
+	}
+}
+
+// rollbackFailed restore revision of failed entries
+func (uow *unitOfWork) rollbackFailed(results []UnitResult) {
+	for i, res := range results {
+		if !res.Succeed && uow.entries[i].rollback != nil {
+			uow.entries[i].rollback()
+		}
+	}
+}
+
+// write run BulkWrite for each collection group
+// entries prepared once and prepared writes reused on transaction retry
+func (uow *unitOfWork) write(ctx context.Context, ordered bool) ([]UnitResult, error) {
+	results := uow.results()
+	opt, err := contextOptionOf(ctx, uow.opts...)
+	if err != nil {
+		return uow.failed(err), err
+	}
+	names := make([]string, len(uow.entries))
+	for i, e := range uow.entries {
+		names[i] = e.model.Collection(opt.Database).Name()
+	}
+
+	var errs error
+	for _, group := range unitGroups(names, ordered) {
+		err := uow.writeGroup(ctx, opt, group, ordered, results)
+		if err != nil {
+			if ordered {
+				return results, err
+			}
+			errs = errors.Join(errs, err)
+		}
+	}
+	return results, errs
+}
+
+// writeGroup prepare and write entries of single collection
+// on ordered mode entries after first failure (including not matched update and delete) not written
+func (uow *unitOfWork) writeGroup(ctx context.Context, opt MongoOption, group []int, ordered bool, results []UnitResult) error {
+	if err := uow.loadOld(ctx, opt, group); err != nil {
+		for _, i := range group {
+			results[i].Err = err
+		}
+		return err
+	}
+
+	// prepare writes (nil write for unchanged updates)
+	var errs error
+	writes := make([]mongo.WriteModel, 0, len(group))
+	positions := make([]int, 0, len(group))
+	for _, i := range group {
+		w, err := uow.prepare(ctx, opt, i)
+		if err != nil {
+			results[i].Err = err
+			errs = errors.Join(errs, err)
+			if ordered {
+				break
+			}
+			continue
+		}
+		if w == nil {
+			results[i].Succeed = true
+			continue
+		}
+		writes = append(writes, w)
+		positions = append(positions, i)
+	}
+	if len(writes) == 0 {
+		return errs
+	}
+
+	// ordered writes split after update and delete to stop on missing or changed record
+	model := uow.entries[positions[0]].model
+	ops := make([]UnitOperation, len(positions))
+	for j, i := range positions {
+		ops[j] = uow.entries[i].op
+	}
+	for _, chunk := range unitChunks(ops, ordered) {
+		err := uow.bulkWrite(ctx, opt, model, writes[chunk[0]:chunk[1]], positions[chunk[0]:chunk[1]], ordered, results)
+		errs = errors.Join(errs, err)
+		if ordered && err != nil {
+			break
+		}
+	}
+	if ordered {
+		stop := -1
+		for _, i := range group {
+			if results[i].Err != nil {
+				stop = i
+				break
+			}
+		}
+		for _, i := range group {
+			if stop >= 0 && i > stop {
+				results[i].Succeed = false
+			}
+		}
+	}
+	if err := uow.audit(ctx, opt, group, results); err != nil {
+		errs = errors.Join(errs, HookError{Hook: "Audit", Result: results, Err: err})
+	}
+	return errs
+}
+
+// bulkWrite write prepared writes of entries in single BulkWrite and resolve entries result
+func (uow *unitOfWork) bulkWrite(ctx context.Context, opt MongoOption, model Model, writes []mongo.WriteModel, positions []int, ordered bool, results []UnitResult) error {
+	log := logOf("unit_of_work", model, opt).pipe(writes)
+	res, err := retryOf(ctx, opt, false, func() (*mongo.BulkWriteResult, error) {
+		option := options.BulkWrite().SetOrdered(ordered)
+		if opt.Comment != "" {
+			option.SetComment(opt.Comment)
+		}
+		return collectionOf(model, opt).BulkWrite(ctx, writes, option)
+	})
+	invalidateCache(ctx, model, opt)
+	err = parseError(err)
+	if res != nil {
+		log.result(res).done(ctx, res.InsertedCount+res.ModifiedCount+res.DeletedCount, err)
+	} else {
+		log.done(ctx, 0, err)
+	}
+
+	succeed, failures := bulkResultsOf(len(writes), ordered, err)
+	for j, i := range positions {
+		results[i].Succeed, results[i].Err = succeed[j], failures[j]
+	}
+	if res != nil {
+		err = errors.Join(err, uow.verify(ctx, opt, positions, results, res))
+	}
+	return err
+}
+
+// unitChunks split writes to BulkWrite chunks as [start, end) ranges
+// update and delete match nothing without server error on missing or changed record
+// so on ordered mode each update and delete end chunk to verify matched records before next writes
+func unitChunks(ops []UnitOperation, ordered bool) [][2]int {
+	if !ordered {
+		return [][2]int{{0, len(ops)}}
+	}
+	res := make([][2]int, 0)
+	start := 0
+	for j, op := range ops {
+		if op != UnitInsert {
+			res = append(res, [2]int{start, j + 1})
+			start = j + 1
+		}
+	}
+	if start < len(ops) {
+		res = append(res, [2]int{start, len(ops)})
+	}
+	return res
+}
+
+// unitGroups group entries index by collection name
+// on ordered mode only consecutive entries of same collection grouped to keep registration order
+func unitGroups(names []string, ordered bool) [][]int {
+	res := make([][]int, 0)
+	groups := make(map[string]int)
+	for i, name := range names {
+		if ordered {
+			if n := len(res); n > 0 && names[res[n-1][0]] == name {
+				res[n-1] = append(res[n-1], i)
+			} else {
+				res = append(res, []int{i})
+			}
+			continue
+		}
+		if g, ok := groups[name]; ok {
+			res[g] = append(res[g], i)
+		} else {
+			groups[name] = len(res)
+			res = append(res, []int{i})
+		}
+	}
+	return res
+}
+
+// bulkResultsOf resolve succeed state and error of each write from BulkWrite error
+// on ordered mode writes after first failure not executed
+func bulkResultsOf(count int, ordered bool, err error) ([]bool, []error) {
+	succeed := make([]bool, count)
+	failures := make([]error, count)
+	var bulkErr mongo.BulkWriteException
+	if err != nil && !errors.As(err, &bulkErr) {
+		for j := range failures {
+			failures[j] = err
+		}
+		return succeed, failures
+	}
+	first := count
+	for _, we := range bulkErr.WriteErrors {
+		if we.Index >= 0 && we.Index < count {
+			failures[we.Index] = writeErrorOf(we.WriteError)
+			first = min(first, we.Index)
+		}
+	}
+	for j := range succeed {
+		if failures[j] == nil && bulkErr.WriteConcernError != nil {
+			failures[j] = err
+		}
+		succeed[j] = failures[j] == nil && (!ordered || j < first)
+	}
+	return succeed, failures
+}
+
+// verify check update and delete entries matched by BulkWrite
+// entries of missing or changed records marked as failed with ErrNotFound or ConflictError
+func (uow *unitOfWork) verify(ctx context.Context, opt MongoOption, idxs []int, results []UnitResult, res *mongo.BulkWriteResult) error {
+	var updates, deletes int64
+	ids := make([]any, 0)
+	for _, i := range idxs {
+		if op := uow.entries[i].op; results[i].Succeed && op != UnitInsert {
+			if op == UnitUpdate {
+				updates++
+			} else {
+				deletes++
+			}
+			ids = append(ids, uow.entries[i].model.GetID())
+		}
+	}
+	if res.MatchedCount >= updates && res.DeletedCount >= deletes {
+		return nil
+	}
+
+	model := uow.entries[idxs[0]].model
+	stored := make(map[primitive.ObjectID]bson.Raw)
+	if cur, err := collectionOf(model, opt).Find(ctx, In("_id", ids...)); err != nil {
+		return err
+	} else {
+		defer cur.Close(ctx)
+		for cur.Next(ctx) {
+			if id, ok := cur.Current.Lookup("_id").ObjectIDOK(); ok {
+				stored[id] = bytes.Clone(cur.Current)
+			}
+		}
+		if err := cur.Err(); err != nil {
+			return err
+		}
+	}
+
+	var errs error
+	for _, i := range idxs {
+		e := uow.entries[i]
+		if !results[i].Succeed || e.op == UnitInsert {
+			continue
+		}
+		var err error
+		raw, exists := stored[e.model.GetID()]
+		rev, versioned := parseAsInterface[RevisionVersioning](e.model)
+		switch {
+		case !exists && e.op == UnitUpdate:
+			err = ErrNotFound
+		case exists && e.op == UnitUpdate && versioned:
+			current, _ := raw.Lookup(rev.RevisionField()).AsInt64OK()
+			if current != rev.GetRevision() {
+				e.rollback()
+				err = conflictOf(e.model)
+			}
+		case exists && e.op == UnitDelete && versioned:
+			err = conflictOf(e.model)
+		case exists && e.op == UnitDelete:
+			err = ErrNotFound
+		}
+		if err != nil {
+			results[i].Succeed, results[i].Err = false, err
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
+
+// audit record succeed entries changes of auditable collection group
+func (uow *unitOfWork) audit(ctx context.Context, opt MongoOption, idxs []int, results []UnitResult) error {
+	aud, ok := parseAsInterface[Auditable](uow.entries[idxs[0]].model)
+	if !ok {
+		return nil
+	}
+	records := make([]any, 0)
+	for _, i := range idxs {
+		e := uow.entries[i]
+		if !results[i].Succeed {
+			continue
+		}
+		var err error
+		var action string
+		var changes ChangeSet
+		switch e.op {
+		case UnitInsert:
+			action = OpInsert
+			changes, err = Diff(nil, e.model)
+		case UnitUpdate:
+			action, changes = OpUpdate, e.changes
+		default:
+			action = OpDelete
+			changes, err = Diff(e.old, nil)
+		}
+		if err != nil {
+			return err
+		}
+		if !changes.IsEmpty() {
+			records = append(records, recordOf(ctx, e.model, action, e.model.GetID(), changes, opt))
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	_, err := aud.AuditCollection(opt.Database).InsertMany(ctx, records)
+	return err
+}
+
+// prepare generate entry write model once
+// prepared write reused to prevent running hooks and increasing revision again on transaction retry
+func (uow *unitOfWork) prepare(ctx context.Context, opt MongoOption, i int) (mongo.WriteModel, error) {
+	if e := uow.entries[i]; e.prepared {
+		return e.write, e.err
+	}
+	w, err := uow.prepareWrite(ctx, opt, i)
+	uow.entries[i].prepared, uow.entries[i].write, uow.entries[i].err = true, w, err
+	return w, err
+}
+
+// prepareWrite run pre hooks and generate entry write model
+// nil write returned for unchanged update
+func (uow *unitOfWork) prepareWrite(ctx context.Context, opt MongoOption, i int) (mongo.WriteModel, error) {
+	e := uow.entries[i]
+	switch e.op {
+	case UnitInsert:
+		e.model.Cleanup()
+		e.model.FillCreatedAt()
+		FillBackupFields(e.model)
+		if e.model.GetID().IsZero() {
+			e.model.NewId()
+		}
+		if err := stampTenant(e.model, opt); err != nil {
+			return nil, err
+		}
+		if !opt.IgnoreHooks {
+			if err := e.model.OnInsert(ctx, uow.opts...); err != nil {
+				return nil, err
+			}
+		}
+		return mongo.NewInsertOneModel().SetDocument(e.model), nil
+	case UnitUpdate:
+		if e.old == nil {
+			return nil, ErrNotFound
+		}
+		if !opt.IgnoreGuards && !editableOf(e) {
+			return nil, ErrNotEditable
+		}
+		e.model.Cleanup()
+		fillUpdateFields(e.old, e.model, e.silent)
+		if err := stampTenant(e.model, opt); err != nil {
+			return nil, err
+		}
+		if !opt.IgnoreHooks {
+			if err := e.model.OnUpdate(ctx, uow.opts...); err != nil {
+				return nil, err
+			}
+		}
+		filter, rollback, _ := revisionFilter(e.model, opt, true)
+		update, changes, err := updateOf(e.old, e.model)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		uow.entries[i].changes = changes
+		uow.entries[i].rollback = rollback
+		if len(update) == 0 {
+			return nil, nil
+		}
+		return mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(update), nil
+	default:
+		if e.old == nil {
+			return nil, ErrNotFound
+		}
+		if !opt.IgnoreGuards && !deletableOf(e) {
+			return nil, ErrNotDeletable
+		}
+		if err := stampTenant(e.model, opt); err != nil {
+			return nil, err
+		}
+		if !opt.IgnoreHooks {
+			if err := e.model.OnDelete(ctx, uow.opts...); err != nil {
+				return nil, err
+			}
+		}
+		filter, _, _ := revisionFilter(e.model, opt, false)
+		return mongo.NewDeleteOneModel().
+			SetFilter(filter), nil
+	}
+}
+
+// editableOf check if stored version of entry (or entry model if not loaded) is editable
+func editableOf(e unitEntry) bool {
+	if old, ok := e.old.(Model); ok {
+		return old.IsEditable()
+	}
+	return e.model.IsEditable()
+}
+
+// deletableOf check if stored version of entry (or entry model if not loaded) is deletable
+func deletableOf(e unitEntry) bool {
+	if old, ok := e.old.(Model); ok {
+		return old.IsDeletable()
+	}
+	return e.model.IsDeletable()
+}
+
+// loadOld load old version of update and delete entries using model pipeline
+func (uow *unitOfWork) loadOld(ctx context.Context, opt MongoOption, idxs []int) error {
+	loaded := make([]int, 0)
+	ids := make([]any, 0)
+	for _, i := range idxs {
+		if e := uow.entries[i]; e.op != UnitInsert && !e.prepared {
+			loaded = append(loaded, i)
+			ids = append(ids, uow.entries[i].model.GetID())
+		}
+	}
+	if len(loaded) == 0 {
+		return nil
+	}
+
+	model := uow.entries[loaded[0]].model
+	pipeline, err := modelPipeline(model, withTrashed(opt))
+	if err != nil {
+		return err
+	}
+	raws := make(map[primitive.ObjectID]bson.Raw)
+	if cur, err := aggregate(ctx, collectionOf(model, opt), pipeline.Match(In("_id", ids...)).Build(), opt); err != nil {
+		return err
+	} else {
+		defer cur.Close(ctx)
+		for cur.Next(ctx) {
+			if id, ok := cur.Current.Lookup("_id").ObjectIDOK(); ok {
+				raws[id] = bytes.Clone(cur.Current)
+			}
+		}
+		if err := cur.Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range loaded {
+		if raw, ok := raws[uow.entries[i].model.GetID()]; ok {
+			old := reflect.New(reflect.TypeOf(uow.entries[i].model).Elem()).Interface()
+			if err := bson.Unmarshal(raw, old); err != nil {
+				return err
+			}
+			uow.entries[i].old = old
+		}
+	}
+	return nil
+}
+
+// afterWrite run post hooks of succeed entries
+func (uow *unitOfWork) afterWrite(ctx context.Context, results []UnitResult) error {
+	opt := optionOf(uow.opts...)
+	if opt.IgnoreHooks {
+		return nil
+	}
+	var errs error
+	for i, res := range results {
+		if !res.Succeed {
+			continue
+		}
+		switch res.Operation {
+		case UnitInsert:
+			errs = errors.Join(errs, res.Model.OnInserted(ctx, uow.opts...))
+		case UnitUpdate:
+			if e := uow.entries[i]; !e.changes.IsEmpty() {
+				ctx := context.WithValue(ctx, changesKey{}, e.changes)
+				errs = errors.Join(errs, res.Model.OnUpdated(e.old, ctx, uow.opts...))
+			}
+		case UnitDelete:
+			errs = errors.Join(errs, res.Model.OnDeleted(ctx, uow.opts...))
+		}
+	}
+	if errs != nil {
+		return HookError{Hook: "UnitOfWork", Result: results, Err: errs}
+	}
+	return nil
+}
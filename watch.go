@@ -0,0 +1,224 @@
+package mongoutils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChangeEvent typed change stream event
+type ChangeEvent[T any] struct {
+	// Operation event operation type (insert, update, replace, delete, ...)
+	Operation string
+	// ID changed document id
+	ID primitive.ObjectID
+	// Document full document (current version for update, nil for delete)
+	Document *T
+	// Changes updated and removed fields of update event
+	Changes ChangeSet
+	// ClusterTime event time
+	ClusterTime primitive.Timestamp
+	// Token event resume token
+	Token bson.Raw
+}
+
+// WatchOption change stream option
+type WatchOption struct {
+	// Name consumer name to persist resume token (token not persisted on empty name)
+	Name string
+	// Pipeline change events filter (e.g. match operationType)
+	Pipeline MongoPipeline
+	// TokenCollection resume tokens collection name in model database (default resume_tokens)
+	TokenCollection string
+}
+
+// changeEvent raw change stream event
+type changeEvent[T any] struct {
+	ID                       bson.Raw `bson:"_id"`
+	OperationType            string   `bson:"operationType"`
+	DocumentKey              bson.Raw `bson:"documentKey"`
+	FullDocument             *T       `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.Raw `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
+		UpdatedFields primitive.M `bson:"updatedFields"`
+		RemovedFields []string    `bson:"removedFields"`
+	} `bson:"updateDescription"`
+	ClusterTime primitive.Timestamp `bson:"clusterTime"`
+}
+
+// resumeToken persisted consumer resume token
+type resumeToken struct {
+	Name       string    `bson:"_id"`
+	Collection string    `bson:"collection"`
+	Token      bson.Raw  `bson:"token"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
+
+// Watch watch model collection changes and pass typed events to callback
+// this function block until context canceled, callback returns error or stream failed
+// return ErrStopIteration from callback to stop watching without error
+// on named mode resume token persisted after each processed event and watch resumed from last token
+// watch of MultiTenant models request pre-images to resolve tenant of delete events
+// and return ErrPreImageRequired if delete event has no pre-image and tenant is not part of document key
+// change streams require replica set or sharded cluster
+// only context version available because watch is long running operation
+//
+// @param ctx operation context (use context without timeout)
+// @param wo watch option
+// @param cb callback to call for each event
+// @opts operation option
+func WatchCtx[T any](
+	ctx context.Context,
+	wo WatchOption,
+	cb func(event ChangeEvent[T]) error,
+	opts ...MongoOption,
+) error {
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	coll := model.Collection(opt.Database)
+	tokens := tokenCollectionOf(coll, wo)
+
+	option := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if wo.Name != "" {
+		stored := new(resumeToken)
+		if err := tokens.FindOne(ctx, primitive.M{"_id": wo.Name}).Decode(stored); err == nil {
+			option.SetResumeAfter(stored.Token)
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+	}
+	pipeline, err := watchTenantPipeline(model, opt)
+	if err != nil {
+		return err
+	}
+	field, _, tenanted, _ := tenantOf(model, opt)
+	if tenanted {
+		option.SetFullDocumentBeforeChange(options.WhenAvailable)
+	}
+	if wo.Pipeline != nil {
+		pipeline = append(pipeline, wo.Pipeline.Build()...)
+	}
+
+	stream, err := coll.Watch(ctx, pipeline, option)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+	for stream.Next(ctx) {
+		raw := new(changeEvent[T])
+		if err := stream.Decode(raw); err != nil {
+			return err
+		}
+		if tenanted && tenantUnknown(raw.OperationType, raw.DocumentKey, raw.FullDocumentBeforeChange, field) {
+			return ErrPreImageRequired
+		}
+		id, _ := raw.DocumentKey.Lookup("_id").ObjectIDOK()
+		event := ChangeEvent[T]{
+			Operation:   raw.OperationType,
+			ID:          id,
+			Document:    raw.FullDocument,
+			ClusterTime: raw.ClusterTime,
+			Token:       raw.ID,
+			Changes: ChangeSet{
+				Set:   raw.UpdateDescription.UpdatedFields,
+				Unset: raw.UpdateDescription.RemovedFields,
+			},
+		}
+		if err := cb(event); errors.Is(err, ErrStopIteration) {
+			return saveResumeToken(ctx, tokens, wo, coll.Name(), event.Token)
+		} else if err != nil {
+			return err
+		}
+		if err := saveResumeToken(ctx, tokens, wo, coll.Name(), event.Token); err != nil {
+			return err
+		}
+	}
+	if errors.Is(stream.Err(), context.Canceled) {
+		return nil
+	}
+	return stream.Err()
+}
+
+// watchTenantPipeline generate change stream tenant match stage of MultiTenant models
+// document events matched by full document, document key or pre-image tenant field
+// delete events without pre-image and document key tenant passed to report missing pre-image
+func watchTenantPipeline(model Model, opt MongoOption) (mongo.Pipeline, error) {
+	field, tenant, ok, err := tenantOf(model, opt)
+	if !ok {
+		return mongo.Pipeline{}, err
+	}
+	return NewPipe().
+		Match(primitive.M{"$or": primitive.A{
+			primitive.M{"fullDocument." + field: tenant},
+			primitive.M{"documentKey." + field: tenant},
+			primitive.M{"fullDocumentBeforeChange." + field: tenant},
+			primitive.M{
+				"operationType":            "delete",
+				"fullDocumentBeforeChange": nil,
+				"documentKey." + field:     primitive.M{"$exists": false},
+			},
+			In("operationType", "drop", "rename", "dropDatabase", "invalidate"),
+		}}).
+		Build(), nil
+}
+
+// tenantUnknown check if tenant of delete event not resolvable from pre-image or document key
+func tenantUnknown(operation string, documentKey bson.Raw, preImage bson.Raw, field string) bool {
+	if operation != "delete" || len(preImage) > 0 {
+		return false
+	}
+	_, err := documentKey.LookupErr(field)
+	return err != nil
+}
+
+// ResumeTokenOf get persisted resume token of watch consumer (nil if not persisted)
+//
+// @param ctx operation context
+// @param wo watch option
+// @opts operation option
+func ResumeTokenOf[T any](ctx context.Context, wo WatchOption, opts ...MongoOption) (bson.Raw, error) {
+	model := typeModelSafe[T]()
+	opt, err := contextOptionOf(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	stored := new(resumeToken)
+	if err := tokenCollectionOf(model.Collection(opt.Database), wo).FindOne(ctx, primitive.M{"_id": wo.Name}).Decode(stored); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return stored.Token, nil
+}
+
+// tokenCollectionOf get resume tokens collection
+func tokenCollectionOf(coll *mongo.Collection, wo WatchOption) *mongo.Collection {
+	name := wo.TokenCollection
+	if name == "" {
+		name = "resume_tokens"
+	}
+	return coll.Database().Collection(name)
+}
+
+// saveResumeToken persist consumer resume token
+func saveResumeToken(ctx context.Context, tokens *mongo.Collection, wo WatchOption, collection string, token bson.Raw) error {
+	if wo.Name == "" {
+		return nil
+	}
+	_, err := tokens.UpdateOne(
+		ctx,
+		primitive.M{"_id": wo.Name},
+		Set(primitive.M{"collection": collection, "token": token, "updated_at": time.Now().UTC()}),
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
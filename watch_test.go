@@ -0,0 +1,147 @@
+package mongoutils_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomig/mongoutils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type watchItem struct {
+	mongoutils.BaseModel `bson:",inline"`
+	Name                 string `bson:"name"`
+}
+
+func (*watchItem) TypeName() string {
+	return "watch_item"
+}
+
+func (*watchItem) Collection(db *mongo.Database) *mongo.Collection {
+	return db.Collection("watch_items")
+}
+
+// TestWatch require local single-node replica set (mongod --replSet rs0 and rs.initiate())
+func TestWatch(t *testing.T) {
+	host := "mongodb://127.0.0.1:27017/?directConnection=true"
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(host).SetServerSelectionTimeout(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skip("mongodb not available: " + err.Error())
+	}
+
+	db := client.Database("test")
+	opt := mongoutils.MongoOption{Database: db}
+	wo := mongoutils.WatchOption{
+		Name:     "watch_test",
+		Pipeline: mongoutils.NewPipe().Match(mongoutils.In("operationType", "insert")),
+	}
+	db.Collection("resume_tokens").DeleteOne(ctx, primitive.M{"_id": wo.Name})
+
+	// insert records until watcher receive event
+	go func() {
+		for ctx.Err() == nil {
+			mongoutils.InsertCtx(ctx, &watchItem{Name: "John"}, opt)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	var received *watchItem
+	err = mongoutils.WatchCtx(ctx, wo, func(event mongoutils.ChangeEvent[watchItem]) error {
+		received = event.Document
+		return mongoutils.ErrStopIteration
+	}, opt)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 40573 {
+		t.Skip("mongodb is not replica set")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received == nil || received.Name != "John" {
+		t.Fatalf("invalid event document %v", received)
+	}
+
+	token, err := mongoutils.ResumeTokenOf[watchItem](ctx, wo, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == nil {
+		t.Fatal("resume token not persisted")
+	}
+}
+
+type watchTenantItem struct {
+	watchItem `bson:",inline"`
+	Tenant    string `bson:"tenant_id"`
+}
+
+func (*watchTenantItem) TenantField() string {
+	return "tenant_id"
+}
+
+func (item *watchTenantItem) GetTenant() string {
+	return item.Tenant
+}
+
+func (item *watchTenantItem) SetTenant(tenant string) {
+	item.Tenant = tenant
+}
+
+func TestWatchTenantPipeline(t *testing.T) {
+	pipe, err := mongoutils.WatchTenantPipeline(new(watchTenantItem), mongoutils.MongoOption{Tenant: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := pretty(pipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `[[{"Key":"$match","Value":{"$or":[{"fullDocument.tenant_id":"a"},{"documentKey.tenant_id":"a"},{"fullDocumentBeforeChange.tenant_id":"a"},{"documentKey.tenant_id":{"$exists":false},"fullDocumentBeforeChange":null,"operationType":"delete"},{"operationType":{"$in":["drop","rename","dropDatabase","invalidate"]}}]}}]]` {
+		t.Log(v)
+		t.Fatal("fail tenant pipeline")
+	}
+
+	// tenant required
+	if _, err := mongoutils.WatchTenantPipeline(new(watchTenantItem), mongoutils.MongoOption{}); !errors.Is(err, mongoutils.ErrTenantRequired) {
+		t.Fatal("fail tenant required")
+	}
+
+	// not tenant model
+	if pipe, err := mongoutils.WatchTenantPipeline(new(watchItem), mongoutils.MongoOption{}); err != nil || len(pipe) != 0 {
+		t.Fatal("fail non tenant model")
+	}
+}
+
+func TestTenantUnknown(t *testing.T) {
+	key, _ := bson.Marshal(primitive.M{"_id": primitive.NewObjectID()})
+	shardKey, _ := bson.Marshal(primitive.M{"_id": primitive.NewObjectID(), "tenant_id": "a"})
+	pre, _ := bson.Marshal(primitive.M{"tenant_id": "a"})
+	cases := []struct {
+		name      string
+		operation string
+		key       bson.Raw
+		pre       bson.Raw
+		expected  bool
+	}{
+		{"insert", "insert", key, nil, false},
+		{"delete without pre-image", "delete", key, nil, true},
+		{"delete with pre-image", "delete", key, pre, false},
+		{"delete with shard key", "delete", shardKey, nil, false},
+	}
+	for _, c := range cases {
+		if got := mongoutils.TenantUnknown(c.operation, c.key, c.pre, "tenant_id"); got != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+		}
+	}
+}